@@ -133,6 +133,12 @@ func (b *Buffer) TrimNewline() {
 	}
 }
 
+// Truncate discards all but the first n bytes of the buffer. n must not be
+// negative or greater than the length of the buffer.
+func (b *Buffer) Truncate(n int) {
+	b.bs = b.bs[:n]
+}
+
 // Free returns the Buffer to its Pool.
 //
 // Callers must not retain references to the Buffer after calling Free.
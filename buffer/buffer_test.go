@@ -50,6 +50,7 @@ func TestBufferWrites(t *testing.T) {
 		{"AppendTime", func() { buf.AppendTime(time.Date(2000, 1, 2, 3, 4, 5, 6, time.UTC), time.RFC3339) }, "2000-01-02T03:04:05Z"},
 		{"WriteByte", func() { buf.WriteByte('v') }, "v"},
 		{"WriteString", func() { buf.WriteString("foo") }, "foo"},
+		{"Truncate", func() { buf.AppendString("foobar"); buf.Truncate(3) }, "foo"},
 	}
 
 	for _, tt := range tests {
@@ -21,18 +21,24 @@
 package zap
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"sort"
 	"time"
 
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v2"
 )
 
 // SamplingConfig sets a sampling strategy for the logger. Sampling caps the
 // global CPU and I/O load that logging puts on your process while attempting
 // to preserve a representative subset of your logs.
 //
-// If specified, the Sampler will invoke the Hook after each decision.
+// If specified, the Sampler will invoke the Hook after each decision, and
+// take its parameters from the Control so that they can be changed at
+// runtime. See zapcore.SamplerControls.
 //
 // Values configured here are per-second. See zapcore.NewSamplerWithOptions for
 // details.
@@ -40,6 +46,7 @@ type SamplingConfig struct {
 	Initial    int                                           `json:"initial" yaml:"initial"`
 	Thereafter int                                           `json:"thereafter" yaml:"thereafter"`
 	Hook       func(zapcore.Entry, zapcore.SamplingDecision) `json:"-" yaml:"-"`
+	Control    *zapcore.SamplerControl                       `json:"-" yaml:"-"`
 }
 
 // Config offers a declarative way to construct a logger. It doesn't do
@@ -55,11 +62,20 @@ type SamplingConfig struct {
 //
 // For an example showing runtime log level changes, see the documentation for
 // AtomicLevel.
+//
+// A Config can also be set from environment variables and command-line flags;
+// see ApplyEnv and RegisterFlags. Settings from a file are overridden by the
+// environment, which is overridden by flags.
 type Config struct {
 	// Level is the minimum enabled logging level. Note that this is a dynamic
 	// level, so calling Config.Level.SetLevel will atomically change the log
 	// level of all loggers descended from this config.
 	Level AtomicLevel `json:"level" yaml:"level"`
+	// NamedLevels sets levels per logger name, overriding Level for the
+	// loggers its rules apply to. Level is the root level of the registry
+	// unless it already has one. Keep a reference to the registry to change
+	// its rules at runtime. A nil registry disables per-name levels.
+	NamedLevels *LevelRegistry `json:"namedLevels,omitempty" yaml:"namedLevels,omitempty"`
 	// Development puts the logger in development mode, which changes the
 	// behavior of DPanicLevel and takes stacktraces more liberally.
 	Development bool `json:"development" yaml:"development"`
@@ -93,6 +109,76 @@ type Config struct {
 	InitialFields map[string]interface{} `json:"initialFields" yaml:"initialFields"`
 }
 
+// plainConfig is a Config without its unmarshaling methods.
+type plainConfig Config
+
+// UnmarshalYAML unmarshals YAML into the Config. If the encoder config has a
+// levelEncoder mapping, Level accepts the mapped values too, so a
+// configuration that maps warn to WARNING may set its level to WARNING.
+func (cfg *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw map[string]interface{}
+	if err := unmarshal(&raw); err == nil {
+		_, hasLevel := raw["level"]
+		encoderConfig, _ := raw["encoderConfig"].(map[interface{}]interface{})
+		cfg.useLevelMapping(hasLevel, func(v interface{}) error {
+			data, err := yaml.Marshal(encoderConfig["levelEncoder"])
+			if err != nil {
+				return err
+			}
+			return yaml.Unmarshal(data, v)
+		})
+	}
+	return unmarshal((*plainConfig)(cfg))
+}
+
+// UnmarshalJSON unmarshals JSON into the Config, accepting mapped level
+// values like UnmarshalYAML does.
+func (cfg *Config) UnmarshalJSON(data []byte) error {
+	cfg.useJSONLevelMapping(data)
+	return json.Unmarshal(data, (*plainConfig)(cfg))
+}
+
+func (cfg *Config) useJSONLevelMapping(data []byte) {
+	var raw struct {
+		Level         json.RawMessage `json:"level"`
+		EncoderConfig struct {
+			LevelEncoder json.RawMessage `json:"levelEncoder"`
+		} `json:"encoderConfig"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return
+	}
+	cfg.useLevelMapping(raw.Level != nil, func(v interface{}) error {
+		return json.Unmarshal(raw.EncoderConfig.LevelEncoder, v)
+	})
+}
+
+// useLevelMapping makes Level accept the values that the levelEncoder
+// mapping decoded by decode serializes levels to. Anything other than a
+// valid mapping is left for the Config's own unmarshaling to handle.
+func (cfg *Config) useLevelMapping(hasLevel bool, decode func(interface{}) error) {
+	var o struct {
+		Mapping map[string]interface{} `json:"mapping" yaml:"mapping"`
+	}
+	if err := decode(&o); err != nil || len(o.Mapping) == 0 {
+		return
+	}
+	var enc zapcore.LevelEncoder
+	if err := decode(&enc); err != nil {
+		return
+	}
+	if cfg.Level.l == nil {
+		if !hasLevel {
+			return
+		}
+		cfg.Level = NewAtomicLevel()
+	}
+	if cfg.Level.s == nil {
+		cfg.Level.s = newAtomicLevelState()
+	}
+	cfg.Level.setAliases(enc)
+}
+
 // NewProductionEncoderConfig returns an opinionated EncoderConfig for
 // production environments.
 func NewProductionEncoderConfig() zapcore.EncoderConfig {
@@ -149,6 +235,7 @@ func NewDevelopmentEncoderConfig() zapcore.EncoderConfig {
 		EncodeTime:     zapcore.ISO8601TimeEncoder,
 		EncodeDuration: zapcore.StringDurationEncoder,
 		EncodeCaller:   zapcore.ShortCallerEncoder,
+		ColorMode:      zapcore.ColorAuto,
 	}
 }
 
@@ -171,28 +258,43 @@ func NewDevelopmentConfig() Config {
 
 // Build constructs a logger from the Config and Options.
 func (cfg Config) Build(opts ...Option) (*Logger, error) {
+	log, _, err := cfg.build(opts)
+	return log, err
+}
+
+// build builds a Logger like Build, and also returns a function that closes
+// the Logger's outputs.
+func (cfg Config) build(opts []Option) (*Logger, func(), error) {
 	enc, err := cfg.buildEncoder()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	sink, errSink, err := cfg.openSinks()
+	sink, errSink, closeSinks, err := cfg.openSinks()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if cfg.Level == (AtomicLevel{}) {
-		return nil, fmt.Errorf("missing Level")
+		closeSinks()
+		return nil, nil, fmt.Errorf("missing Level")
+	}
+
+	var level zapcore.LevelEnabler = cfg.Level
+	if cfg.NamedLevels != nil {
+		cfg.NamedLevels.bindRoot(cfg.Level)
+		level = cfg.NamedLevels
 	}
 
-	log := New(
-		zapcore.NewCore(enc, sink, cfg.Level),
-		cfg.buildOptions(errSink)...,
-	)
+	var core zapcore.Core = zapcore.NewCore(enc, sink, level)
+	if cfg.Development && cfg.EncoderConfig.DuplicateKeys == zapcore.ReportDuplicateKey {
+		core = duplicateKeyPanicCore{core}
+	}
+	log := New(core, cfg.buildOptions(errSink)...)
 	if len(opts) > 0 {
 		log = log.WithOptions(opts...)
 	}
-	return log, nil
+	return log, closeSinks, nil
 }
 
 func (cfg Config) buildOptions(errSink zapcore.WriteSyncer) []Option {
@@ -220,6 +322,9 @@ func (cfg Config) buildOptions(errSink zapcore.WriteSyncer) []Option {
 			if scfg.Hook != nil {
 				samplerOpts = append(samplerOpts, zapcore.SamplerHook(scfg.Hook))
 			}
+			if scfg.Control != nil {
+				samplerOpts = append(samplerOpts, zapcore.SamplerControls(scfg.Control))
+			}
 			return zapcore.NewSamplerWithOptions(
 				core,
 				time.Second,
@@ -230,6 +335,12 @@ func (cfg Config) buildOptions(errSink zapcore.WriteSyncer) []Option {
 		}))
 	}
 
+	// Filter by name outside of sampling, so that entries dropped by name
+	// don't count towards the sampling limits.
+	if cfg.NamedLevels != nil {
+		opts = append(opts, NamedLevels(cfg.NamedLevels))
+	}
+
 	if len(cfg.InitialFields) > 0 {
 		fs := make([]Field, 0, len(cfg.InitialFields))
 		keys := make([]string, 0, len(cfg.InitialFields))
@@ -246,19 +357,79 @@ func (cfg Config) buildOptions(errSink zapcore.WriteSyncer) []Option {
 	return opts
 }
 
-func (cfg Config) openSinks() (zapcore.WriteSyncer, zapcore.WriteSyncer, error) {
+func (cfg Config) openSinks() (sink, errSink zapcore.WriteSyncer, close func(), err error) {
 	sink, closeOut, err := Open(cfg.OutputPaths...)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-	errSink, _, err := Open(cfg.ErrorOutputPaths...)
+	errSink, closeErr, err := Open(cfg.ErrorOutputPaths...)
 	if err != nil {
 		closeOut()
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-	return sink, errSink, nil
+	return sink, errSink, func() {
+		closeOut()
+		closeErr()
+	}, nil
 }
 
 func (cfg Config) buildEncoder() (zapcore.Encoder, error) {
-	return newEncoder(cfg.Encoding, cfg.EncoderConfig)
+	encoderConfig := cfg.EncoderConfig
+	encoderConfig.ColorMode = cfg.colorMode()
+	return newEncoder(cfg.Encoding, encoderConfig)
+}
+
+// duplicateKeyPanicCore handles duplicate keys found by the
+// zapcore.ReportDuplicateKey policy in development. Like DPanicLevel, it
+// panics, but only once the entry has been written. Outside of development,
+// the error is reported to the logger's error output like any other write
+// error.
+type duplicateKeyPanicCore struct {
+	zapcore.Core
+}
+
+func (c duplicateKeyPanicCore) With(fields []zapcore.Field) zapcore.Core {
+	return duplicateKeyPanicCore{c.Core.With(fields)}
+}
+
+func (c duplicateKeyPanicCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// Add ourselves rather than the wrapped core, so that we see its errors.
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c duplicateKeyPanicCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	err := c.Core.Write(ent, fields)
+	if dup, ok := err.(*zapcore.DuplicateKeyError); ok {
+		panic(dup.Error())
+	}
+	return err
+}
+
+// colorMode resolves zapcore.ColorAuto against the configured output paths.
+// Only the standard streams can be terminals; any other output disables
+// color unless it's forced by the environment.
+func (cfg Config) colorMode() zapcore.ColorMode {
+	mode := cfg.EncoderConfig.ColorMode
+	if mode != zapcore.ColorAuto {
+		return mode
+	}
+
+	outputs := make([]zapcore.WriteSyncer, 0, len(cfg.OutputPaths))
+	for _, path := range cfg.OutputPaths {
+		switch path {
+		case "stdout":
+			outputs = append(outputs, os.Stdout)
+		case "stderr":
+			outputs = append(outputs, os.Stderr)
+		default:
+			outputs = append(outputs, zapcore.AddSync(ioutil.Discard))
+		}
+	}
+	if mode.Enabled(zapcore.NewMultiWriteSyncer(outputs...)) {
+		return zapcore.ColorAlways
+	}
+	return zapcore.ColorNever
 }
@@ -21,6 +21,8 @@
 package zap
 
 import (
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -29,6 +31,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v2"
 )
 
 func TestConfig(t *testing.T) {
@@ -211,3 +214,136 @@ func TestConfigWithSamplingHook(t *testing.T) {
 	assert.Equal(t, int64(expectDropped), dcount.Load())
 	assert.Equal(t, int64(expectSampled), scount.Load())
 }
+
+func TestConfigColorMode(t *testing.T) {
+	for _, key := range []string{"NO_COLOR", "FORCE_COLOR"} {
+		if prev, ok := os.LookupEnv(key); ok {
+			os.Unsetenv(key)
+			defer os.Setenv(key, prev)
+		}
+	}
+
+	temp, err := ioutil.TempFile("", "zap-color-config-test")
+	require.NoError(t, err, "Failed to create temp file.")
+	defer os.Remove(temp.Name())
+
+	cfg := NewDevelopmentConfig()
+	cfg.OutputPaths = []string{temp.Name()}
+	cfg.EncoderConfig.TimeKey = ""
+	cfg.EncoderConfig.CallerKey = ""
+	cfg.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+
+	logger, err := cfg.Build()
+	require.NoError(t, err, "Unexpected error constructing logger.")
+	logger.Info("auto")
+
+	cfg.EncoderConfig.ColorMode = zapcore.ColorAlways
+	logger, err = cfg.Build()
+	require.NoError(t, err, "Unexpected error constructing logger.")
+	logger.Info("always")
+
+	byteContents, err := ioutil.ReadAll(temp)
+	require.NoError(t, err, "Couldn't read log contents from temp file.")
+	assert.Equal(
+		t,
+		"INFO\tauto\n\x1b[34mINFO\x1b[0m\talways\n",
+		string(byteContents),
+		"Expected color only when forced, since the output isn't a terminal.",
+	)
+}
+
+func TestConfigReportDuplicateKey(t *testing.T) {
+	errOut, err := ioutil.TempFile("", "zap-duplicate-key-test")
+	require.NoError(t, err, "Failed to create temp file.")
+	defer os.Remove(errOut.Name())
+
+	cfg := NewProductionConfig()
+	cfg.OutputPaths = nil
+	cfg.ErrorOutputPaths = []string{errOut.Name()}
+	cfg.EncoderConfig.DuplicateKeys = zapcore.ReportDuplicateKey
+
+	logger, err := cfg.Build()
+	require.NoError(t, err, "Unexpected error constructing logger.")
+	logger.With(String("id", "ctx")).Info("hello", String("id", "call"))
+
+	byteContents, err := ioutil.ReadAll(errOut)
+	require.NoError(t, err, "Couldn't read error output from temp file.")
+	assert.Contains(t, string(byteContents), `duplicate key "id" in log entry`, "Expected duplicate key to be reported.")
+
+	out, err := ioutil.TempFile("", "zap-duplicate-key-test")
+	require.NoError(t, err, "Failed to create temp file.")
+	defer os.Remove(out.Name())
+
+	cfg.Development = true
+	cfg.OutputPaths = []string{out.Name()}
+	logger, err = cfg.Build()
+	require.NoError(t, err, "Unexpected error constructing logger.")
+	assert.Panics(t, func() {
+		logger.With(String("id", "ctx")).Info("hello", String("id", "call"))
+	}, "Expected duplicate key to panic in development.")
+
+	byteContents, err = ioutil.ReadAll(out)
+	require.NoError(t, err, "Couldn't read log contents from temp file.")
+	assert.Contains(t, string(byteContents), `"id":"ctx","id_2":"call"`, "Expected entry to be written before panicking.")
+}
+
+func TestConfigLevelMapping(t *testing.T) {
+	const (
+		yamlDoc = `
+level: WARNING
+encoding: json
+outputPaths: [%q]
+encoderConfig:
+  messageKey: msg
+  levelKey: level
+  levelEncoder:
+    mapping: {warn: WARNING, error: test-config-ERR}
+`
+		jsonDoc = `{
+  "level": "WARNING",
+  "encoding": "json",
+  "outputPaths": [%q],
+  "encoderConfig": {
+    "messageKey": "msg",
+    "levelKey": "level",
+    "levelEncoder": {"mapping": {"warn": "WARNING", "error": "test-config-ERR"}}
+  }
+}`
+	)
+	tests := []struct {
+		desc      string
+		doc       string
+		unmarshal func([]byte, *Config) error
+	}{
+		{"yaml", yamlDoc, func(data []byte, cfg *Config) error { return yaml.Unmarshal(data, cfg) }},
+		{"strict yaml", yamlDoc, func(data []byte, cfg *Config) error { return cfg.UnmarshalYAMLStrict(data) }},
+		{"json", jsonDoc, func(data []byte, cfg *Config) error { return json.Unmarshal(data, cfg) }},
+		{"strict json", jsonDoc, func(data []byte, cfg *Config) error { return cfg.UnmarshalJSONStrict(data) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			out, err := ioutil.TempFile("", "zap-level-mapping-test")
+			require.NoError(t, err, "Failed to create temp file.")
+			defer os.Remove(out.Name())
+
+			var cfg Config
+			require.NoError(t, tt.unmarshal([]byte(fmt.Sprintf(tt.doc, out.Name())), &cfg), "Unexpected error unmarshaling config.")
+			assert.Equal(t, WarnLevel, cfg.Level.Level(), "Expected level to be set by its mapped name.")
+
+			logger, err := cfg.Build()
+			require.NoError(t, err, "Unexpected error constructing logger.")
+			logger.Info("dropped")
+			logger.Warn("kept")
+
+			require.NoError(t, cfg.Level.UnmarshalText([]byte("test-config-ERR")), "Expected level to accept mapped names.")
+			assert.Equal(t, ErrorLevel, cfg.Level.Level(), "Unexpected level after unmarshaling mapped name.")
+			var global zapcore.Level
+			assert.Error(t, global.UnmarshalText([]byte("test-config-ERR")), "Expected mapped names to stay scoped to the config.")
+
+			byteContents, err := ioutil.ReadAll(out)
+			require.NoError(t, err, "Couldn't read log contents from temp file.")
+			assert.Equal(t, `{"level":"WARNING","msg":"kept"}`+"\n", string(byteContents), "Unexpected log output.")
+		})
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v2"
+)
+
+// A ConfigError describes a problem with one setting of a Config.
+type ConfigError struct {
+	// Path is the setting's path in YAML, like "encoderConfig.timeEncoder"
+	// or "outputPaths[2]".
+	Path string
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Path, e.Err)
+}
+
+// Validate checks the Config for settings that Build rejects, like unknown
+// encodings and sinks, and for settings that Build accepts but that can't be
+// what was meant, like sampling that drops every entry after the first ones.
+// It doesn't open any outputs.
+//
+// Validate returns every problem it finds, each as a *ConfigError. Use
+// multierr.Errors to split them.
+func (cfg Config) Validate() error {
+	var errs error
+	report := func(path string, format string, args ...interface{}) {
+		errs = multierr.Append(errs, &ConfigError{Path: path, Err: fmt.Errorf(format, args...)})
+	}
+
+	if cfg.Level == (AtomicLevel{}) {
+		report("level", "missing")
+	}
+
+	if _, err := lookupEncoder(cfg.Encoding); err != nil {
+		report("encoding", "%v", err)
+	}
+
+	enc := cfg.EncoderConfig
+	if enc.LevelKey != "" && enc.EncodeLevel == nil {
+		report("encoderConfig.levelEncoder", "required when levelKey is set")
+	}
+	if enc.TimeKey != "" && enc.EncodeTime == nil {
+		report("encoderConfig.timeEncoder", "required when timeKey is set")
+	}
+	if enc.CallerKey != "" && enc.EncodeCaller == nil {
+		report("encoderConfig.callerEncoder", "required when callerKey is set")
+	}
+	for _, limit := range []struct {
+		key   string
+		value int
+	}{
+		{"maxStringLength", enc.MaxStringLength},
+		{"maxArrayLength", enc.MaxArrayLength},
+		{"maxDepth", enc.MaxDepth},
+		{"maxEntrySize", enc.MaxEntrySize},
+	} {
+		if limit.value < 0 {
+			report("encoderConfig."+limit.key, "must not be negative, got %v", limit.value)
+		}
+	}
+
+	if s := cfg.Sampling; s != nil {
+		if s.Initial < 0 {
+			report("sampling.initial", "must not be negative, got %v", s.Initial)
+		}
+		if s.Thereafter <= 0 {
+			report("sampling.thereafter", "must be positive, got %v", s.Thereafter)
+		}
+	}
+
+	errs = multierr.Append(errs, validatePaths("outputPaths", cfg.OutputPaths))
+	errs = multierr.Append(errs, validatePaths("errorOutputPaths", cfg.ErrorOutputPaths))
+	return errs
+}
+
+// validatePaths checks that each path names a registered sink, without
+// opening it.
+func validatePaths(key string, paths []string) error {
+	var errs error
+	for i, path := range paths {
+		u, _, err := lookupSink(path)
+		if err == nil && u.Scheme == schemeFile {
+			err = checkFileURL(u)
+		}
+		if err != nil {
+			errs = multierr.Append(errs, &ConfigError{Path: fmt.Sprintf("%v[%d]", key, i), Err: err})
+		}
+	}
+	return errs
+}
+
+// UnmarshalYAMLStrict unmarshals YAML into the Config, like yaml.Unmarshal,
+// but rejects keys that don't match any setting, and names of level, time,
+// duration, caller and name encoders that aren't registered, which
+// yaml.Unmarshal silently replaces with defaults. Unknown names are reported
+// as *ConfigErrors. Settings missing from the YAML keep their current values.
+// It doesn't call Validate.
+func (cfg *Config) UnmarshalYAMLStrict(data []byte) error {
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return err
+	}
+	var names configEncoderNames
+	if err := yaml.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	return names.check()
+}
+
+// UnmarshalJSONStrict unmarshals JSON into the Config, like json.Unmarshal,
+// but rejects keys that don't match any setting, and unknown encoder names,
+// as UnmarshalYAMLStrict does. Settings missing from the JSON keep their
+// current values. It doesn't call Validate.
+func (cfg *Config) UnmarshalJSONStrict(data []byte) error {
+	cfg.useJSONLevelMapping(data)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode((*plainConfig)(cfg)); err != nil {
+		return err
+	}
+	var names configEncoderNames
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	return names.check()
+}
+
+// configEncoderNames holds the names of the primitive type encoders in a
+// configuration file, which the encoders' UnmarshalText methods don't check.
+type configEncoderNames struct {
+	EncoderConfig struct {
+		LevelEncoder    encoderName `json:"levelEncoder" yaml:"levelEncoder"`
+		TimeEncoder     encoderName `json:"timeEncoder" yaml:"timeEncoder"`
+		DurationEncoder encoderName `json:"durationEncoder" yaml:"durationEncoder"`
+		CallerEncoder   encoderName `json:"callerEncoder" yaml:"callerEncoder"`
+		NameEncoder     encoderName `json:"nameEncoder" yaml:"nameEncoder"`
+	} `json:"encoderConfig" yaml:"encoderConfig"`
+}
+
+func (n configEncoderNames) check() error {
+	enc := n.EncoderConfig
+	var errs error
+	for _, e := range []struct {
+		key   string
+		kind  string
+		name  encoderName
+		known func(string) bool
+	}{
+		{"levelEncoder", "level", enc.LevelEncoder, func(name string) bool {
+			_, ok := zapcore.LookupLevelEncoder(name)
+			return ok
+		}},
+		{"timeEncoder", "time", enc.TimeEncoder, func(name string) bool {
+			_, ok := zapcore.LookupTimeEncoder(name)
+			return ok
+		}},
+		{"durationEncoder", "duration", enc.DurationEncoder, func(name string) bool {
+			_, ok := zapcore.LookupDurationEncoder(name)
+			return ok
+		}},
+		{"callerEncoder", "caller", enc.CallerEncoder, func(name string) bool {
+			_, ok := zapcore.LookupCallerEncoder(name)
+			return ok
+		}},
+		{"nameEncoder", "name", enc.NameEncoder, func(name string) bool {
+			_, ok := zapcore.LookupNameEncoder(name)
+			return ok
+		}},
+	} {
+		if e.name.name == "" || e.known(e.name.name) {
+			continue
+		}
+		path := "encoderConfig." + e.key
+		if e.name.inObject {
+			path += ".name"
+		}
+		errs = multierr.Append(errs, &ConfigError{
+			Path: path,
+			Err:  fmt.Errorf("unknown %v encoder %q", e.kind, e.name.name),
+		})
+	}
+	return errs
+}
+
+// encoderName is the name of an encoder, given either as a string or as the
+// "name" field of an object. Other forms, like a time encoder's layout, have
+// no name.
+type encoderName struct {
+	name     string
+	inObject bool
+}
+
+func (n *encoderName) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&n.name); err == nil {
+		return nil
+	}
+	var o struct {
+		Name string `json:"name" yaml:"name"`
+	}
+	if err := unmarshal(&o); err == nil {
+		n.name, n.inObject = o.Name, true
+	}
+	// Values of the wrong type are reported when the Config is unmarshaled.
+	return nil
+}
+
+func (n *encoderName) UnmarshalJSON(data []byte) error {
+	return n.UnmarshalYAML(func(v interface{}) error {
+		return json.Unmarshal(data, v)
+	})
+}
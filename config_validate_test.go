@@ -0,0 +1,235 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		desc      string
+		modify    func(*Config)
+		wantPaths []string
+	}{
+		{
+			desc:   "production",
+			modify: func(*Config) {},
+		},
+		{
+			desc:   "development",
+			modify: func(cfg *Config) { *cfg = NewDevelopmentConfig() },
+		},
+		{
+			desc:      "missing level",
+			modify:    func(cfg *Config) { cfg.Level = AtomicLevel{} },
+			wantPaths: []string{"level"},
+		},
+		{
+			desc:      "missing encoding",
+			modify:    func(cfg *Config) { cfg.Encoding = "" },
+			wantPaths: []string{"encoding"},
+		},
+		{
+			desc:      "unknown encoding",
+			modify:    func(cfg *Config) { cfg.Encoding = "xml" },
+			wantPaths: []string{"encoding"},
+		},
+		{
+			desc: "missing primitive encoders",
+			modify: func(cfg *Config) {
+				cfg.EncoderConfig.EncodeLevel = nil
+				cfg.EncoderConfig.EncodeTime = nil
+				cfg.EncoderConfig.EncodeCaller = nil
+			},
+			wantPaths: []string{
+				"encoderConfig.levelEncoder",
+				"encoderConfig.timeEncoder",
+				"encoderConfig.callerEncoder",
+			},
+		},
+		{
+			desc: "omitted keys don't need encoders",
+			modify: func(cfg *Config) {
+				cfg.EncoderConfig.TimeKey = ""
+				cfg.EncoderConfig.EncodeTime = nil
+			},
+		},
+		{
+			desc: "negative limits",
+			modify: func(cfg *Config) {
+				cfg.EncoderConfig.MaxDepth = -1
+				cfg.EncoderConfig.MaxEntrySize = -2
+			},
+			wantPaths: []string{"encoderConfig.maxDepth", "encoderConfig.maxEntrySize"},
+		},
+		{
+			desc:      "invalid sampling",
+			modify:    func(cfg *Config) { cfg.Sampling = &SamplingConfig{Initial: -1, Thereafter: 0} },
+			wantPaths: []string{"sampling.initial", "sampling.thereafter"},
+		},
+		{
+			desc: "invalid paths",
+			modify: func(cfg *Config) {
+				cfg.OutputPaths = []string{"stdout", "/tmp/app.log", "kafka://broker/topic", "file://host/tmp/app.log"}
+				cfg.ErrorOutputPaths = []string{"%zz"}
+			},
+			wantPaths: []string{"outputPaths[2]", "outputPaths[3]", "errorOutputPaths[0]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			cfg := NewProductionConfig()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if len(tt.wantPaths) == 0 {
+				assert.NoError(t, err, "Unexpected validation error.")
+				return
+			}
+
+			var paths []string
+			for _, err := range multierr.Errors(err) {
+				cerr, ok := err.(*ConfigError)
+				require.True(t, ok, "Expected a *ConfigError, got %T.", err)
+				assert.Contains(t, cerr.Error(), cerr.Path+": ", "Expected error message to start with path.")
+				paths = append(paths, cerr.Path)
+			}
+			assert.Equal(t, tt.wantPaths, paths, "Unexpected problems.")
+		})
+	}
+}
+
+func TestConfigUnmarshalStrict(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		cfg := NewProductionConfig()
+		require.NoError(t, cfg.UnmarshalYAMLStrict([]byte("level: debug\nencoding: console\n")),
+			"Unexpected error unmarshaling known keys.")
+		assert.Equal(t, DebugLevel, cfg.Level.Level(), "Unexpected level.")
+		assert.Equal(t, "console", cfg.Encoding, "Unexpected encoding.")
+		assert.NotNil(t, cfg.Sampling, "Expected missing keys to keep their values.")
+
+		err := cfg.UnmarshalYAMLStrict([]byte("level: debug\nencoderConfig:\n  timekey: time\n"))
+		require.Error(t, err, "Expected error unmarshaling unknown key.")
+		assert.Contains(t, err.Error(), "timekey", "Expected error to name the unknown key.")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		cfg := NewProductionConfig()
+		require.NoError(t, cfg.UnmarshalJSONStrict([]byte(`{"level": "warn", "outputPaths": ["stdout"]}`)),
+			"Unexpected error unmarshaling known keys.")
+		assert.Equal(t, WarnLevel, cfg.Level.Level(), "Unexpected level.")
+		assert.Equal(t, []string{"stdout"}, cfg.OutputPaths, "Unexpected output paths.")
+
+		err := cfg.UnmarshalJSONStrict([]byte(`{"sampling": {"initial": 1, "burst": 2}}`))
+		require.Error(t, err, "Expected error unmarshaling unknown key.")
+		assert.Contains(t, err.Error(), "burst", "Expected error to name the unknown key.")
+	})
+}
+
+func TestConfigUnmarshalStrictEncoderNames(t *testing.T) {
+	tests := []struct {
+		desc      string
+		unmarshal func(*Config) error
+		wantPaths []string
+	}{
+		{
+			desc: "known yaml names",
+			unmarshal: func(cfg *Config) error {
+				return cfg.UnmarshalYAMLStrict([]byte(`
+encoderConfig:
+  levelEncoder: capital
+  timeEncoder: {name: iso8601, timeZone: UTC}
+  durationEncoder: ms
+  callerEncoder: trailing:2
+  nameEncoder: full
+`))
+			},
+		},
+		{
+			desc: "unnamed yaml forms",
+			unmarshal: func(cfg *Config) error {
+				return cfg.UnmarshalYAMLStrict([]byte(`
+encoderConfig:
+  levelEncoder: {mapping: {info: INF}}
+  timeEncoder: {layout: "15:04"}
+`))
+			},
+		},
+		{
+			desc: "unknown yaml names",
+			unmarshal: func(cfg *Config) error {
+				return cfg.UnmarshalYAMLStrict([]byte(`
+encoderConfig:
+  levelEncoder: captial
+  timeEncoder: {name: isoo8601}
+  durationEncoder: millis
+  callerEncoder: trailing:none
+  nameEncoder: fully
+`))
+			},
+			wantPaths: []string{
+				"encoderConfig.levelEncoder",
+				"encoderConfig.timeEncoder.name",
+				"encoderConfig.durationEncoder",
+				"encoderConfig.callerEncoder",
+				"encoderConfig.nameEncoder",
+			},
+		},
+		{
+			desc: "known json names",
+			unmarshal: func(cfg *Config) error {
+				return cfg.UnmarshalJSONStrict([]byte(`{"encoderConfig": {"timeEncoder": "rfc3339", "callerEncoder": "short"}}`))
+			},
+		},
+		{
+			desc: "unknown json names",
+			unmarshal: func(cfg *Config) error {
+				return cfg.UnmarshalJSONStrict([]byte(`{"encoderConfig": {"timeEncoder": {"name": "epochs"}, "callerEncoder": "shrot"}}`))
+			},
+			wantPaths: []string{"encoderConfig.timeEncoder.name", "encoderConfig.callerEncoder"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			cfg := NewProductionConfig()
+			err := tt.unmarshal(&cfg)
+			if len(tt.wantPaths) == 0 {
+				assert.NoError(t, err, "Unexpected error unmarshaling encoder names.")
+				return
+			}
+
+			var paths []string
+			for _, err := range multierr.Errors(err) {
+				cerr, ok := err.(*ConfigError)
+				require.True(t, ok, "Expected a *ConfigError, got %T.", err)
+				assert.Contains(t, cerr.Error(), "unknown", "Expected error to report an unknown name.")
+				paths = append(paths, cerr.Path)
+			}
+			assert.Equal(t, tt.wantPaths, paths, "Unexpected problems.")
+		})
+	}
+}
@@ -0,0 +1,449 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v2"
+)
+
+// A ConfigWatcher keeps a Logger in sync with a configuration file. It polls
+// the file for changes, and when it changes, builds a new Core from it and
+// swaps it into the Logger, and any Loggers derived from it, atomically.
+// Entries being written during a swap finish writing to the old Core before
+// its outputs are closed. Entries checked before a swap but written after it
+// are checked again and written with the new Core.
+//
+// The level, sampling, encoder options, initial fields, output paths, error
+// output paths and named levels are reloaded. Settings that configure the
+// Logger rather than its Core, like Development, DisableCaller and
+// DisableStacktrace, keep their values from the first load. The Logger's level
+// is an AtomicLevel that stays the same across reloads; reloading sets it to
+// the level in the file.
+//
+// If the file can't be loaded, or fails Config.Validate, the Logger keeps its
+// current configuration, and the error is logged. Since the file may be read
+// while it's being written, replace it atomically, for example by renaming a
+// new file over it. Empty files are treated as errors.
+type ConfigWatcher struct {
+	path  string
+	opts  []Option
+	level AtomicLevel
+	core  *swapRoot
+	log   *Logger
+
+	mu        sync.Mutex // serializes reloads
+	data      []byte     // contents last loaded
+	stat      os.FileInfo
+	pollErr   string // last error logged by poll, to log each one once
+	stop      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// WatchConfig builds a Logger from the configuration file at path, like
+// Config.Build with the given Options, and reloads it whenever the file
+// changes, checking every interval. Files with a .json extension are parsed
+// as JSON, and others as YAML. Settings missing from the file default to
+// those of NewProductionConfig.
+//
+// Call Stop to stop watching the file.
+func WatchConfig(path string, interval time.Duration, opts ...Option) (*ConfigWatcher, error) {
+	w := &ConfigWatcher{
+		path:    path,
+		opts:    opts,
+		level:   NewAtomicLevel(),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	log, closeSinks, err := w.build(data)
+	if err != nil {
+		return nil, err
+	}
+
+	w.stat, w.data = stat, data
+	w.core = newSwapRoot(log.Core(), log.errorOutput, closeSinks)
+	w.log = log.WithOptions(
+		WrapCore(func(zapcore.Core) zapcore.Core {
+			return &swappableCore{root: w.core}
+		}),
+		ErrorOutput(swappableErrorOutput{w.core}),
+	)
+
+	go w.watch(interval)
+	return w, nil
+}
+
+// Logger returns the Logger whose Core follows the configuration file.
+func (w *ConfigWatcher) Logger() *Logger {
+	return w.log
+}
+
+// Level returns the Logger's level, which reloads set to the level in the
+// file. It can also be changed between reloads.
+func (w *ConfigWatcher) Level() AtomicLevel {
+	return w.level
+}
+
+// Reload loads the configuration file now, if its contents changed since
+// they were last loaded, and swaps in a new Core built from it. If the file
+// can't be loaded, Reload returns the error and keeps the current Core.
+func (w *ConfigWatcher) Reload() error {
+	data, err := ioutil.ReadFile(w.path)
+	if err != nil {
+		return err
+	}
+	_, err = w.reload(data)
+	return err
+}
+
+// Stop stops watching the configuration file. The Logger keeps its current
+// configuration.
+func (w *ConfigWatcher) Stop() {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+	})
+	<-w.stopped
+}
+
+func (w *ConfigWatcher) watch(interval time.Duration) {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+// poll reloads the file if its size or modification time changed since it
+// was last read. Until the file can be read, it's retried on every poll, but
+// each distinct error is logged only once.
+func (w *ConfigWatcher) poll() {
+	stat, err := os.Stat(w.path)
+	if err == nil {
+		w.mu.Lock()
+		changed := stat.Size() != w.stat.Size() || !stat.ModTime().Equal(w.stat.ModTime())
+		w.mu.Unlock()
+		if !changed {
+			return
+		}
+	}
+
+	var reloaded bool
+	if err == nil {
+		var data []byte
+		if data, err = ioutil.ReadFile(w.path); err == nil {
+			w.mu.Lock()
+			w.stat = stat
+			w.mu.Unlock()
+			reloaded, err = w.reload(data)
+		}
+	}
+	if err != nil {
+		if msg := err.Error(); msg != w.pollErr {
+			w.pollErr = msg
+			w.log.Error("couldn't reload logging configuration, keeping the current one",
+				String("path", w.path), Error(err))
+		}
+		return
+	}
+	w.pollErr = ""
+	if reloaded {
+		w.log.Info("reloaded logging configuration", String("path", w.path))
+	}
+}
+
+func (w *ConfigWatcher) reload(data []byte) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if bytes.Equal(data, w.data) {
+		return false, nil
+	}
+
+	log, closeSinks, err := w.build(data)
+	if err != nil {
+		return false, err
+	}
+	w.core.swap(log.Core(), log.errorOutput, closeSinks)
+	w.data = data
+	return true, nil
+}
+
+// build parses a configuration file and builds a Logger from it, using the
+// watcher's level.
+func (w *ConfigWatcher) build(data []byte) (*Logger, func(), error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil, fmt.Errorf("%v is empty", w.path)
+	}
+
+	cfg := NewProductionConfig()
+	unmarshal := yaml.Unmarshal
+	if filepath.Ext(w.path) == ".json" {
+		unmarshal = json.Unmarshal
+	}
+	if err := unmarshal(data, &cfg); err != nil {
+		return nil, nil, fmt.Errorf("couldn't parse %v: %v", w.path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid config in %v: %v", w.path, err)
+	}
+
+	lvl := cfg.Level.Level()
+	cfg.Level = w.level
+	log, closeSinks, err := cfg.build(w.opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't build logger from %v: %v", w.path, err)
+	}
+	w.level.SetLevel(lvl)
+	return log, closeSinks, nil
+}
+
+// swapRoot holds the current version of a Core, and the error output that
+// goes with it, that can be replaced.
+type swapRoot struct {
+	current atomic.Value // *swapVersion
+}
+
+// swapVersion is one version of a swapRoot's Core. Writes hold a reference
+// to the version they write to, so that a swap doesn't close its outputs
+// while they're in progress.
+type swapVersion struct {
+	gen         uint64
+	core        zapcore.Core
+	errorOutput zapcore.WriteSyncer
+	closeSinks  func() // closes the outputs
+
+	mu      sync.Mutex
+	refs    int
+	retired bool // replaced by a newer version
+	closed  bool // retired with no references left
+}
+
+func newSwapRoot(core zapcore.Core, errorOutput zapcore.WriteSyncer, closeSinks func()) *swapRoot {
+	r := &swapRoot{}
+	r.current.Store(&swapVersion{core: core, errorOutput: errorOutput, closeSinks: closeSinks})
+	return r
+}
+
+func (r *swapRoot) load() *swapVersion {
+	return r.current.Load().(*swapVersion)
+}
+
+// acquire returns the current version, which the caller must release once
+// it's done writing to it.
+func (r *swapRoot) acquire() *swapVersion {
+	for {
+		// A version that's already closed has been replaced, so try again.
+		if v := r.load(); v.tryAcquire() {
+			return v
+		}
+	}
+}
+
+// swap replaces the current version. The replaced version is synced and
+// closed once the writes in progress are done. Callers must serialize swaps.
+func (r *swapRoot) swap(core zapcore.Core, errorOutput zapcore.WriteSyncer, closeSinks func()) {
+	old := r.load()
+	r.current.Store(&swapVersion{gen: old.gen + 1, core: core, errorOutput: errorOutput, closeSinks: closeSinks})
+
+	old.mu.Lock()
+	old.retired = true
+	closing := old.closeIfUnused()
+	old.mu.Unlock()
+	if closing {
+		old.closeOutputs()
+	}
+}
+
+// tryAcquire takes a reference to the version, unless it's closed.
+func (v *swapVersion) tryAcquire() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.closed {
+		return false
+	}
+	v.refs++
+	return true
+}
+
+func (v *swapVersion) release() {
+	v.mu.Lock()
+	v.refs--
+	closing := v.closeIfUnused()
+	v.mu.Unlock()
+	if closing {
+		v.closeOutputs()
+	}
+}
+
+// closeIfUnused marks a retired version without references as closed,
+// reporting whether the caller should close its outputs. It must be called
+// with mu held.
+func (v *swapVersion) closeIfUnused() bool {
+	if !v.retired || v.refs > 0 || v.closed {
+		return false
+	}
+	v.closed = true
+	return true
+}
+
+func (v *swapVersion) closeOutputs() {
+	v.core.Sync()
+	v.errorOutput.Sync()
+	if v.closeSinks != nil {
+		v.closeSinks()
+	}
+}
+
+// swappableErrorOutput writes to the current error output of a swapRoot.
+type swappableErrorOutput struct {
+	root *swapRoot
+}
+
+func (o swappableErrorOutput) Write(p []byte) (int, error) {
+	v := o.root.acquire()
+	defer v.release()
+	return v.errorOutput.Write(p)
+}
+
+func (o swappableErrorOutput) Sync() error {
+	v := o.root.acquire()
+	defer v.release()
+	return v.errorOutput.Sync()
+}
+
+// swappableCore delegates to the current Core of a swapRoot, with the fields
+// added by With. Derived Cores rebuild their context lazily after a swap.
+type swappableCore struct {
+	root   *swapRoot
+	fields []zapcore.Field
+	cache  atomic.Value // *derivedCore
+}
+
+// derivedCore is a version's Core with a swappableCore's fields added.
+type derivedCore struct {
+	gen  uint64
+	core zapcore.Core
+}
+
+func (c *swappableCore) core(v *swapVersion) zapcore.Core {
+	if len(c.fields) == 0 {
+		return v.core
+	}
+	if d, ok := c.cache.Load().(*derivedCore); ok && d.gen == v.gen {
+		return d.core
+	}
+	d := &derivedCore{gen: v.gen, core: v.core.With(c.fields)}
+	c.cache.Store(d)
+	return d.core
+}
+
+func (c *swappableCore) Enabled(lvl zapcore.Level) bool {
+	return c.core(c.root.load()).Enabled(lvl)
+}
+
+func (c *swappableCore) With(fields []zapcore.Field) zapcore.Core {
+	n := len(c.fields)
+	return &swappableCore{root: c.root, fields: append(c.fields[:n:n], fields...)}
+}
+
+// Check checks the entry against the current version's Core. It doesn't
+// hold the version, since the entry may never be written.
+func (c *swappableCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	v := c.root.acquire()
+	defer v.release()
+	checked := c.check(v, ent)
+	if checked == nil {
+		return ce
+	}
+	return ce.AddCore(ent, &versionedEntry{swappableCore: c, version: v, checked: checked})
+}
+
+func (c *swappableCore) check(v *swapVersion, ent zapcore.Entry) *zapcore.CheckedEntry {
+	checked := c.core(v).Check(ent, nil)
+	if checked != nil {
+		checked.ErrorOutput = v.errorOutput
+	}
+	return checked
+}
+
+func (c *swappableCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	v := c.root.acquire()
+	defer v.release()
+	return c.core(v).Write(ent, fields)
+}
+
+func (c *swappableCore) Sync() error {
+	v := c.root.acquire()
+	defer v.release()
+	return c.core(v).Sync()
+}
+
+// versionedEntry writes an entry checked by one version of a swappableCore's
+// Core. If that version was closed in the meantime, the entry is checked
+// again and written with the current version instead.
+type versionedEntry struct {
+	*swappableCore
+	version *swapVersion
+	checked *zapcore.CheckedEntry
+}
+
+func (e *versionedEntry) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	v, checked := e.version, e.checked
+	if !v.tryAcquire() {
+		v = e.root.acquire()
+		if checked = e.check(v, ent); checked == nil {
+			v.release()
+			return nil
+		}
+	}
+	defer v.release()
+	// The Logger may annotate the entry after it's checked, for example with
+	// its caller.
+	checked.Entry = ent
+	checked.Write(fields...)
+	return nil
+}
@@ -0,0 +1,319 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zap
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/internal/ztest"
+	"go.uber.org/zap/zapcore"
+)
+
+func writeWatchedConfig(t testing.TB, path, level, out string) {
+	cfg := fmt.Sprintf(`
+level: %v
+encoding: json
+outputPaths: [%q]
+errorOutputPaths: [%q]
+initialFields: {config: %v}
+encoderConfig:
+  messageKey: msg
+  levelKey: level
+  timeKey: ""
+  callerKey: ""
+`, level, out, out, level)
+	replaceFile(t, path, cfg)
+}
+
+// replaceFile replaces the contents of a file atomically, so that the watcher
+// never sees it partially written.
+func replaceFile(t testing.TB, path, contents string) {
+	tmp := path + ".tmp"
+	require.NoError(t, ioutil.WriteFile(tmp, []byte(contents), 0644), "Couldn't write file.")
+	require.NoError(t, os.Rename(tmp, path), "Couldn't replace file.")
+}
+
+func readLogFile(t testing.TB, path string) string {
+	contents, err := ioutil.ReadFile(path)
+	require.NoError(t, err, "Couldn't read log file.")
+	return string(contents)
+}
+
+func TestConfigWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zap-config-watcher")
+	require.NoError(t, err, "Couldn't create temp dir.")
+	defer os.RemoveAll(dir)
+
+	var (
+		path   = filepath.Join(dir, "log.yaml")
+		first  = filepath.Join(dir, "first.log")
+		second = filepath.Join(dir, "second.log")
+	)
+	writeWatchedConfig(t, path, "info", first)
+
+	w, err := WatchConfig(path, 5*time.Millisecond)
+	require.NoError(t, err, "Unexpected error watching config.")
+	defer w.Stop()
+
+	log := w.Logger()
+	child := log.With(String("child", "yes"))
+	log.Debug("dropped")
+	child.Info("before")
+	assert.Equal(t, InfoLevel, w.Level().Level(), "Unexpected level.")
+	assert.Equal(t,
+		`{"level":"info","msg":"before","config":"info","child":"yes"}`+"\n",
+		readLogFile(t, first),
+		"Unexpected output from first config.",
+	)
+
+	writeWatchedConfig(t, path, "debug", second)
+	require.NoError(t, w.Reload(), "Unexpected error reloading config.")
+	assert.Equal(t, DebugLevel, w.Level().Level(), "Expected reload to set level.")
+
+	child.Debug("after")
+	assert.Equal(t,
+		`{"level":"debug","msg":"after","config":"debug","child":"yes"}`+"\n",
+		readLogFile(t, second),
+		"Expected derived loggers to use the reloaded config.",
+	)
+	assert.NotContains(t, readLogFile(t, first), "after", "Unexpected output to old path.")
+
+	replaceFile(t, path, "level: [not a level")
+	assert.Error(t, w.Reload(), "Expected error reloading invalid config.")
+	replaceFile(t, path, "\n")
+	assert.Error(t, w.Reload(), "Expected error reloading empty config.")
+	replaceFile(t, path, "sampling: {initial: 1, thereafter: 0}")
+	assert.Error(t, w.Reload(), "Expected error reloading config that fails validation.")
+	log.Debug("kept")
+	assert.Contains(t, readLogFile(t, second), `"msg":"kept"`, "Expected invalid config to keep the current one.")
+}
+
+// watchedSink is an in-memory Sink that records whether it's closed.
+type watchedSink struct {
+	ztest.Buffer
+	closed bool
+}
+
+func (s *watchedSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+// registerWatchedSinks registers a sink scheme whose outputs are kept in
+// memory, keyed by host.
+func registerWatchedSinks(t testing.TB) map[string]*watchedSink {
+	sinks := make(map[string]*watchedSink)
+	require.NoError(t, RegisterSink("watched", func(u *url.URL) (Sink, error) {
+		if s, ok := sinks[u.Host]; ok {
+			return s, nil
+		}
+		s := &watchedSink{}
+		sinks[u.Host] = s
+		return s, nil
+	}), "Couldn't register sink.")
+	return sinks
+}
+
+func TestConfigWatcherInFlightEntries(t *testing.T) {
+	defer resetSinkRegistry()
+	sinks := registerWatchedSinks(t)
+
+	dir, err := ioutil.TempDir("", "zap-config-watcher")
+	require.NoError(t, err, "Couldn't create temp dir.")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "log.yaml")
+	writeWatchedConfig(t, path, "info", "watched://first")
+
+	w, err := WatchConfig(path, time.Hour)
+	require.NoError(t, err, "Unexpected error watching config.")
+	defer w.Stop()
+
+	log := w.Logger()
+	log.Info("before")
+	ce := log.Check(InfoLevel, "in flight")
+	require.NotNil(t, ce, "Expected entry to be enabled.")
+	childCE := log.With(String("child", "yes")).Check(InfoLevel, "child in flight")
+	require.NotNil(t, childCE, "Expected child entry to be enabled.")
+	dropped := log.Check(InfoLevel, "never written")
+	require.NotNil(t, dropped, "Expected entry to be enabled.")
+
+	// Checked entries don't keep the old outputs open, even if they're
+	// never written.
+	writeWatchedConfig(t, path, "debug", "watched://second")
+	require.NoError(t, w.Reload(), "Unexpected error reloading config.")
+	first, second := sinks["first"], sinks["second"]
+	assert.True(t, first.closed, "Expected old outputs to be closed.")
+	assert.False(t, second.closed, "Expected new outputs to stay open.")
+
+	// Entries written after the reload are checked again and written with
+	// the reloaded config.
+	ce.Write(String("k", "v"))
+	childCE.Write()
+	assert.Equal(t,
+		[]string{`{"level":"info","msg":"before","config":"info"}`},
+		first.Lines(),
+		"Unexpected output to old outputs.",
+	)
+	assert.Equal(t,
+		[]string{
+			`{"level":"info","msg":"in flight","config":"debug","k":"v"}`,
+			`{"level":"info","msg":"child in flight","config":"debug","child":"yes"}`,
+		},
+		second.Lines(),
+		"Expected entries written after the reload to use the reloaded config.",
+	)
+}
+
+func TestSwapRootClosesAfterRelease(t *testing.T) {
+	closed := 0
+	root := newSwapRoot(zapcore.NewNopCore(), zapcore.AddSync(ioutil.Discard), func() { closed++ })
+
+	v := root.acquire()
+	root.swap(zapcore.NewNopCore(), zapcore.AddSync(ioutil.Discard), nil)
+	assert.Equal(t, 0, closed, "Expected version to stay open while it's held.")
+	v.release()
+	assert.Equal(t, 1, closed, "Expected version to close once released.")
+
+	root.acquire().release()
+	root.swap(zapcore.NewNopCore(), zapcore.AddSync(ioutil.Discard), nil)
+	assert.Equal(t, 1, closed, "Expected each version to close once.")
+}
+
+func TestConfigWatcherPolling(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zap-config-watcher")
+	require.NoError(t, err, "Couldn't create temp dir.")
+	defer os.RemoveAll(dir)
+
+	var (
+		path = filepath.Join(dir, "log.yaml")
+		out  = filepath.Join(dir, "out.log")
+	)
+	writeWatchedConfig(t, path, "info", out)
+
+	w, err := WatchConfig(path, time.Millisecond)
+	require.NoError(t, err, "Unexpected error watching config.")
+	defer w.Stop()
+
+	waitForLog := func(substr string) {
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(readLogFile(t, out), substr) {
+			require.True(t, time.Now().Before(deadline), "Timed out waiting for %q in log output.", substr)
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	writeWatchedConfig(t, path, "debug", out)
+	waitForLog("reloaded logging configuration")
+	assert.Equal(t, DebugLevel, w.Level().Level(), "Expected poll to reload level.")
+
+	replaceFile(t, path, "level: [not a level")
+	waitForLog("couldn't reload logging configuration")
+	assert.Equal(t, DebugLevel, w.Level().Level(), "Expected invalid config to keep level.")
+
+	w.Stop()
+	writeWatchedConfig(t, path, "warn", out)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, DebugLevel, w.Level().Level(), "Expected no reloads after Stop.")
+}
+
+func TestConfigWatcherPollErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zap-config-watcher")
+	require.NoError(t, err, "Couldn't create temp dir.")
+	defer os.RemoveAll(dir)
+
+	var (
+		path = filepath.Join(dir, "log.yaml")
+		out  = filepath.Join(dir, "out.log")
+	)
+	writeWatchedConfig(t, path, "info", out)
+
+	w, err := WatchConfig(path, time.Hour)
+	require.NoError(t, err, "Unexpected error watching config.")
+	defer w.Stop()
+
+	errorsLogged := func() int {
+		return strings.Count(readLogFile(t, out), "couldn't reload logging configuration")
+	}
+
+	// Each distinct error is logged once, however often it recurs.
+	require.NoError(t, os.Remove(path), "Couldn't remove config.")
+	for i := 0; i < 3; i++ {
+		w.poll()
+	}
+	assert.Equal(t, 1, errorsLogged(), "Expected a missing file to be logged once.")
+
+	require.NoError(t, os.Mkdir(path, 0755), "Couldn't replace config with a directory.")
+	for i := 0; i < 3; i++ {
+		w.poll()
+	}
+	assert.Equal(t, 2, errorsLogged(), "Expected an unreadable file to be logged once.")
+
+	// A file that couldn't be read is retried, even if its size and
+	// modification time don't change.
+	stat, err := os.Stat(path)
+	require.NoError(t, err, "Couldn't stat directory.")
+	cfg := "level: debug\n"
+	if stat.Size() < int64(len(cfg)) {
+		t.Skipf("Directory size %v is too small to impersonate with a config.", stat.Size())
+	}
+	cfg += strings.Repeat(" ", int(stat.Size())-len(cfg))
+	require.NoError(t, os.Remove(path), "Couldn't remove directory.")
+	require.NoError(t, ioutil.WriteFile(path, []byte(cfg), 0644), "Couldn't write config.")
+	require.NoError(t, os.Chtimes(path, stat.ModTime(), stat.ModTime()), "Couldn't set modification time.")
+	w.poll()
+	assert.Equal(t, DebugLevel, w.Level().Level(), "Expected config to be reloaded once readable.")
+}
+
+func TestWatchConfigErrors(t *testing.T) {
+	_, err := WatchConfig(filepath.Join(os.TempDir(), "zap-missing-config.yaml"), time.Second)
+	assert.Error(t, err, "Expected error watching missing file.")
+
+	f, err := ioutil.TempFile("", "zap-config-*.json")
+	require.NoError(t, err, "Couldn't create temp file.")
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{"level": 42}`)
+	require.NoError(t, err, "Couldn't write config.")
+	require.NoError(t, f.Close(), "Couldn't close config.")
+
+	_, err = WatchConfig(f.Name(), time.Second)
+	assert.Error(t, err, "Expected error watching invalid JSON config.")
+}
+
+func TestSwappableCoreEnabled(t *testing.T) {
+	root := newSwapRoot(zapcore.NewNopCore(), zapcore.AddSync(ioutil.Discard), nil)
+	core := (&swappableCore{root: root}).With([]zapcore.Field{String("k", "v")})
+	assert.False(t, core.Enabled(ErrorLevel), "Nop core shouldn't be enabled.")
+
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+	root.swap(zapcore.NewCore(enc, zapcore.AddSync(ioutil.Discard), DebugLevel), zapcore.AddSync(ioutil.Discard), nil)
+	assert.True(t, core.Enabled(DebugLevel), "Expected derived core to follow swap.")
+	assert.NoError(t, core.Sync(), "Unexpected error syncing.")
+}
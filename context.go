@@ -0,0 +1,64 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zap
+
+import "context"
+
+// loggerKey is the context.Context key under which NewContext stores a
+// Logger. It's unexported so that only this package can set it.
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx that carries logger. Retrieve it with
+// FromContext.
+func NewContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the Logger carried by ctx. If ctx is nil or doesn't
+// carry a Logger, it returns the global Logger (see L), so callers never
+// need to check for nil.
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey{}).(*Logger); ok && l != nil {
+			return l
+		}
+	}
+	return L()
+}
+
+// SugarFromContext returns the Logger carried by ctx as a SugaredLogger,
+// falling back to the global Logger like FromContext.
+func SugarFromContext(ctx context.Context) *SugaredLogger {
+	return FromContext(ctx).Sugar()
+}
+
+// ContextWithFields returns a copy of ctx that carries the Logger from
+// FromContext(ctx) with the given fields added. Code further down the call
+// chain that uses FromContext logs with these fields.
+func ContextWithFields(ctx context.Context, fields ...Field) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(fields...))
+}
+
+// ContextWithOptions returns a copy of ctx that carries the Logger from
+// FromContext(ctx) with the given options applied.
+func ContextWithOptions(ctx context.Context, opts ...Option) context.Context {
+	return NewContext(ctx, FromContext(ctx).WithOptions(opts...))
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zap
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextLogger(t *testing.T) {
+	withLogger(t, DebugLevel, nil, func(logger *Logger, logs *observer.ObservedLogs) {
+		ctx := NewContext(context.Background(), logger)
+		assert.Equal(t, logger, FromContext(ctx), "Expected the stored Logger.")
+
+		ctx = ContextWithFields(ctx, String("request", "abc"))
+		ctx = ContextWithFields(ctx, String("tenant", "acme"))
+		FromContext(ctx).Info("with fields")
+		SugarFromContext(ctx).Infow("sugared", "k", 1)
+
+		ctx = ContextWithOptions(ctx, Fields(Bool("opt", true)))
+		FromContext(ctx).Info("with options")
+
+		output := logs.AllUntimed()
+		require.Equal(t, 3, len(output), "Unexpected number of logs written out.")
+		assert.Equal(t, []Field{String("request", "abc"), String("tenant", "acme")}, output[0].Context, "Unexpected context.")
+		assert.Equal(t, []Field{String("request", "abc"), String("tenant", "acme"), Int("k", 1)}, output[1].Context, "Unexpected sugared context.")
+		assert.Equal(t, []Field{String("request", "abc"), String("tenant", "acme"), Bool("opt", true)}, output[2].Context, "Unexpected context with options.")
+	})
+}
+
+func TestContextLoggerFallback(t *testing.T) {
+	withLogger(t, DebugLevel, nil, func(logger *Logger, logs *observer.ObservedLogs) {
+		defer ReplaceGlobals(logger)()
+
+		var nilCtx context.Context
+		assert.Equal(t, logger, FromContext(nilCtx), "Expected global Logger for nil context.")
+		assert.Equal(t, logger, FromContext(context.Background()), "Expected global Logger for empty context.")
+		assert.Equal(t, logger, FromContext(NewContext(context.Background(), nil)), "Expected global Logger for nil stored Logger.")
+
+		ctx := ContextWithFields(context.Background(), String("request", "abc"))
+		FromContext(ctx).Info("fallback")
+		L().Info("global")
+
+		output := logs.AllUntimed()
+		require.Equal(t, 2, len(output), "Unexpected number of logs written out.")
+		assert.Equal(t, []Field{String("request", "abc")}, output[0].Context, "Expected fields on the context's Logger.")
+		assert.Equal(t, []Field{}, output[1].Context, "Unexpected fields on the global Logger.")
+	})
+}
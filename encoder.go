@@ -66,6 +66,14 @@ func newEncoder(name string, encoderConfig zapcore.EncoderConfig) (zapcore.Encod
 		return nil, fmt.Errorf("missing EncodeTime in EncoderConfig")
 	}
 
+	constructor, err := lookupEncoder(name)
+	if err != nil {
+		return nil, err
+	}
+	return constructor(encoderConfig)
+}
+
+func lookupEncoder(name string) (func(zapcore.EncoderConfig) (zapcore.Encoder, error), error) {
 	_encoderMutex.RLock()
 	defer _encoderMutex.RUnlock()
 	if name == "" {
@@ -75,5 +83,5 @@ func newEncoder(name string, encoderConfig zapcore.EncoderConfig) (zapcore.Encod
 	if !ok {
 		return nil, fmt.Errorf("no encoder registered for name %q", name)
 	}
-	return constructor(encoderConfig)
+	return constructor, nil
 }
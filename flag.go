@@ -22,7 +22,12 @@ package zap
 
 import (
 	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
 
+	"go.uber.org/multierr"
 	"go.uber.org/zap/zapcore"
 )
 
@@ -37,3 +42,223 @@ func LevelFlag(name string, defaultLevel zapcore.Level, usage string) *zapcore.L
 	flag.Var(&lvl, name, usage)
 	return &lvl
 }
+
+// ApplyEnv overrides the Config's settings with those set in environment
+// variables. Unset and empty variables are ignored. It returns an error
+// describing every variable that couldn't be parsed.
+//
+//	ZAP_LEVEL                minimum enabled level, like "info"
+//	ZAP_NAMED_LEVELS         per-logger levels, like "db.*=debug,http=warn"
+//	ZAP_ENCODING             encoding, like "json" or "console"
+//	ZAP_OUTPUT_PATHS         comma-separated output paths
+//	ZAP_ERROR_OUTPUT_PATHS   comma-separated error output paths
+//	ZAP_DEVELOPMENT          development mode, "true" or "false"
+//	ZAP_DISABLE_CALLER       disable caller annotations, "true" or "false"
+//	ZAP_DISABLE_STACKTRACE   disable stacktraces, "true" or "false"
+//	ZAP_SAMPLING_INITIAL     initial entries per second kept by sampling
+//	ZAP_SAMPLING_THEREAFTER  keep every Nth entry per second after Initial
+//	ZAP_SAMPLING             enable or disable sampling, "true" or "false"
+//
+// Setting either sampling parameter enables sampling, with the production
+// defaults for the other parameter, unless ZAP_SAMPLING is false.
+//
+// To configure a logger from a file, the environment and command-line flags,
+// unmarshal the file into a Config, call ApplyEnv, and then parse the flags
+// registered with RegisterFlags. Each source overrides the ones before it.
+func (cfg *Config) ApplyEnv() error {
+	return cfg.applyEnv(os.LookupEnv)
+}
+
+func (cfg *Config) applyEnv(lookup func(string) (string, bool)) error {
+	var errs error
+	for _, v := range cfg.vars() {
+		s, ok := lookup(v.env)
+		if !ok || s == "" {
+			continue
+		}
+		if err := v.value.Set(s); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("invalid %v %q: %v", v.env, s, err))
+		}
+	}
+	return errs
+}
+
+// RegisterFlags defines flags for the Config's settings on fs. Each flag is
+// named prefix followed by the name of its setting: "level", "named-levels",
+// "encoding", "output-paths", "error-output-paths", "development",
+// "disable-caller", "disable-stacktrace", "sampling-initial",
+// "sampling-thereafter" and "sampling". They accept the same values as the
+// environment variables read by ApplyEnv.
+//
+// The flags set the Config's fields when they're parsed, so they default to
+// its current settings. For example,
+//
+//	cfg := zap.NewProductionConfig()
+//	cfg.RegisterFlags(flag.CommandLine, "log-")
+//	flag.Parse()
+//	logger, err := cfg.Build()
+//
+// lets users pass -log-level=debug or -log-sampling=false.
+func (cfg *Config) RegisterFlags(fs *flag.FlagSet, prefix string) {
+	for _, v := range cfg.vars() {
+		fs.Var(v.value, prefix+v.flag, v.usage)
+	}
+}
+
+// configVar is a Config setting that can be set from the environment or a
+// command-line flag.
+type configVar struct {
+	flag  string
+	env   string
+	usage string
+	value flag.Value
+}
+
+func (cfg *Config) vars() []configVar {
+	return []configVar{
+		{"level", "ZAP_LEVEL", "minimum enabled logging level", levelValue{cfg}},
+		{"named-levels", "ZAP_NAMED_LEVELS", "comma-separated logger levels, like db.*=debug", namedLevelsValue{cfg}},
+		{"encoding", "ZAP_ENCODING", "log encoding, like json or console", (*stringValue)(&cfg.Encoding)},
+		{"output-paths", "ZAP_OUTPUT_PATHS", "comma-separated URLs or file paths to write logs to", (*pathsValue)(&cfg.OutputPaths)},
+		{"error-output-paths", "ZAP_ERROR_OUTPUT_PATHS", "comma-separated URLs or file paths to write internal errors to", (*pathsValue)(&cfg.ErrorOutputPaths)},
+		{"development", "ZAP_DEVELOPMENT", "enable development mode", (*boolValue)(&cfg.Development)},
+		{"disable-caller", "ZAP_DISABLE_CALLER", "stop annotating logs with the caller", (*boolValue)(&cfg.DisableCaller)},
+		{"disable-stacktrace", "ZAP_DISABLE_STACKTRACE", "stop capturing stacktraces", (*boolValue)(&cfg.DisableStacktrace)},
+		{"sampling-initial", "ZAP_SAMPLING_INITIAL", "entries with the same level and message to keep each second", samplingParamValue{cfg, false}},
+		{"sampling-thereafter", "ZAP_SAMPLING_THEREAFTER", "keep every Nth entry each second after the initial ones", samplingParamValue{cfg, true}},
+		{"sampling", "ZAP_SAMPLING", "enable sampling", samplingValue{cfg}},
+	}
+}
+
+type stringValue string
+
+func (s *stringValue) Set(v string) error {
+	*s = stringValue(v)
+	return nil
+}
+
+func (s *stringValue) String() string { return string(*s) }
+
+type boolValue bool
+
+func (b *boolValue) Set(v string) error {
+	parsed, err := strconv.ParseBool(v)
+	if err != nil {
+		return err
+	}
+	*b = boolValue(parsed)
+	return nil
+}
+
+func (b *boolValue) String() string { return strconv.FormatBool(bool(*b)) }
+
+func (b *boolValue) IsBoolFlag() bool { return true }
+
+// pathsValue is a comma-separated list of paths. Setting it replaces the
+// list, rather than adding to it.
+type pathsValue []string
+
+func (p *pathsValue) Set(v string) error {
+	var paths []string
+	for _, path := range strings.Split(v, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	*p = paths
+	return nil
+}
+
+func (p *pathsValue) String() string { return strings.Join(*p, ",") }
+
+// levelValue sets the Config's Level, like unmarshaling it does.
+type levelValue struct{ cfg *Config }
+
+func (v levelValue) Set(s string) error {
+	return v.cfg.Level.UnmarshalText([]byte(s))
+}
+
+func (v levelValue) String() string {
+	if v.cfg == nil || v.cfg.Level == (AtomicLevel{}) {
+		return ""
+	}
+	return v.cfg.Level.String()
+}
+
+// namedLevelsValue replaces the rules of the Config's LevelRegistry, creating
+// one if necessary.
+type namedLevelsValue struct{ cfg *Config }
+
+func (v namedLevelsValue) Set(s string) error {
+	if v.cfg.NamedLevels == nil {
+		v.cfg.NamedLevels = NewLevelRegistry(AtomicLevel{})
+	}
+	return v.cfg.NamedLevels.UnmarshalText([]byte(s))
+}
+
+func (v namedLevelsValue) String() string {
+	if v.cfg == nil || v.cfg.NamedLevels == nil {
+		return ""
+	}
+	return v.cfg.NamedLevels.String()
+}
+
+// samplingValue enables or disables sampling. Enabling sampling keeps the
+// current parameters, if any.
+type samplingValue struct{ cfg *Config }
+
+func (v samplingValue) Set(s string) error {
+	enabled, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !enabled:
+		v.cfg.Sampling = nil
+	case v.cfg.Sampling == nil:
+		v.cfg.Sampling = &SamplingConfig{Initial: 100, Thereafter: 100}
+	}
+	return nil
+}
+
+func (v samplingValue) String() string {
+	return strconv.FormatBool(v.cfg != nil && v.cfg.Sampling != nil)
+}
+
+func (v samplingValue) IsBoolFlag() bool { return true }
+
+// samplingParamValue sets Initial or Thereafter, enabling sampling if
+// necessary. It copies the SamplingConfig, which may be shared with other
+// Configs, before changing it.
+type samplingParamValue struct {
+	cfg        *Config
+	thereafter bool
+}
+
+func (v samplingParamValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	sampling := SamplingConfig{Initial: 100, Thereafter: 100}
+	if v.cfg.Sampling != nil {
+		sampling = *v.cfg.Sampling
+	}
+	if v.thereafter {
+		sampling.Thereafter = n
+	} else {
+		sampling.Initial = n
+	}
+	v.cfg.Sampling = &sampling
+	return nil
+}
+
+func (v samplingParamValue) String() string {
+	if v.cfg == nil || v.cfg.Sampling == nil {
+		return ""
+	}
+	if v.thereafter {
+		return strconv.Itoa(v.cfg.Sampling.Thereafter)
+	}
+	return strconv.Itoa(v.cfg.Sampling.Initial)
+}
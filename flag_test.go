@@ -21,13 +21,16 @@
 package zap
 
 import (
+	"bytes"
 	"flag"
 	"io/ioutil"
+	"os"
 	"testing"
 
 	"go.uber.org/zap/zapcore"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type flagTestCase struct {
@@ -100,3 +103,152 @@ func TestLevelFlagsAreIndependent(t *testing.T) {
 	assert.Equal(t, InfoLevel, *consoleLevel, "Expected file logging level to remain unchanged.")
 	assert.Equal(t, DebugLevel, *fileLevel, "Expected console logging level to have changed.")
 }
+
+func TestConfigApplyEnv(t *testing.T) {
+	tests := []struct {
+		desc    string
+		env     map[string]string
+		check   func(testing.TB, Config)
+		wantErr []string
+	}{
+		{
+			desc: "no variables",
+			check: func(t testing.TB, cfg Config) {
+				assert.Equal(t, NewProductionConfig().Sampling, cfg.Sampling, "Unexpected sampling.")
+				assert.Equal(t, InfoLevel, cfg.Level.Level(), "Unexpected level.")
+			},
+		},
+		{
+			desc: "all variables",
+			env: map[string]string{
+				"ZAP_LEVEL":               "debug",
+				"ZAP_NAMED_LEVELS":        "db.*=warn",
+				"ZAP_ENCODING":            "console",
+				"ZAP_OUTPUT_PATHS":        "stdout, /tmp/out.log,",
+				"ZAP_ERROR_OUTPUT_PATHS":  "stdout",
+				"ZAP_DEVELOPMENT":         "true",
+				"ZAP_DISABLE_CALLER":      "1",
+				"ZAP_DISABLE_STACKTRACE":  "true",
+				"ZAP_SAMPLING_INITIAL":    "10",
+				"ZAP_SAMPLING_THEREAFTER": "5",
+			},
+			check: func(t testing.TB, cfg Config) {
+				assert.Equal(t, DebugLevel, cfg.Level.Level(), "Unexpected level.")
+				assert.Equal(t, "db.*=warn", cfg.NamedLevels.String(), "Unexpected named levels.")
+				assert.Equal(t, "console", cfg.Encoding, "Unexpected encoding.")
+				assert.Equal(t, []string{"stdout", "/tmp/out.log"}, cfg.OutputPaths, "Unexpected output paths.")
+				assert.Equal(t, []string{"stdout"}, cfg.ErrorOutputPaths, "Unexpected error output paths.")
+				assert.True(t, cfg.Development, "Expected development mode.")
+				assert.True(t, cfg.DisableCaller, "Expected caller to be disabled.")
+				assert.True(t, cfg.DisableStacktrace, "Expected stacktraces to be disabled.")
+				assert.Equal(t, &SamplingConfig{Initial: 10, Thereafter: 5}, cfg.Sampling, "Unexpected sampling.")
+			},
+		},
+		{
+			desc: "empty variables are ignored",
+			env:  map[string]string{"ZAP_LEVEL": "", "ZAP_ENCODING": ""},
+			check: func(t testing.TB, cfg Config) {
+				assert.Equal(t, InfoLevel, cfg.Level.Level(), "Unexpected level.")
+				assert.Equal(t, "json", cfg.Encoding, "Unexpected encoding.")
+			},
+		},
+		{
+			desc: "disable sampling",
+			env:  map[string]string{"ZAP_SAMPLING": "false", "ZAP_SAMPLING_INITIAL": "10"},
+			check: func(t testing.TB, cfg Config) {
+				assert.Nil(t, cfg.Sampling, "Expected sampling to be disabled.")
+			},
+		},
+		{
+			desc: "invalid variables",
+			env: map[string]string{
+				"ZAP_LEVEL":            "loud",
+				"ZAP_DEVELOPMENT":      "maybe",
+				"ZAP_SAMPLING_INITIAL": "lots",
+				"ZAP_ENCODING":         "console",
+			},
+			check: func(t testing.TB, cfg Config) {
+				assert.Equal(t, "console", cfg.Encoding, "Expected valid variables to be applied.")
+			},
+			wantErr: []string{"ZAP_LEVEL", "ZAP_DEVELOPMENT", "ZAP_SAMPLING_INITIAL"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			cfg := NewProductionConfig()
+			err := cfg.applyEnv(func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			})
+			if len(tt.wantErr) > 0 {
+				require.Error(t, err, "Expected error applying environment.")
+				for _, want := range tt.wantErr {
+					assert.Contains(t, err.Error(), want, "Expected error to name the variable.")
+				}
+			} else {
+				require.NoError(t, err, "Unexpected error applying environment.")
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestConfigApplyEnvFromOS(t *testing.T) {
+	const key = "ZAP_ENCODING"
+	orig, ok := os.LookupEnv(key)
+	defer func() {
+		if ok {
+			os.Setenv(key, orig)
+		} else {
+			os.Unsetenv(key)
+		}
+	}()
+
+	require.NoError(t, os.Setenv(key, "console"), "Couldn't set environment variable.")
+	cfg := NewProductionConfig()
+	require.NoError(t, cfg.ApplyEnv(), "Unexpected error applying environment.")
+	assert.Equal(t, "console", cfg.Encoding, "Expected encoding from the environment.")
+}
+
+func TestConfigRegisterFlags(t *testing.T) {
+	sampling := &SamplingConfig{Initial: 100, Thereafter: 100}
+	cfg := NewProductionConfig()
+	cfg.Sampling = sampling
+	require.NoError(t, cfg.applyEnv(func(key string) (string, bool) {
+		if key == "ZAP_LEVEL" {
+			return "warn", true
+		}
+		return "", false
+	}), "Unexpected error applying environment.")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	cfg.RegisterFlags(fs, "log-")
+
+	require.NoError(t, fs.Parse([]string{
+		"-log-level", "error",
+		"-log-output-paths", "stdout,stderr",
+		"-log-disable-caller",
+		"-log-sampling-thereafter", "7",
+		"-log-named-levels", "http=debug",
+	}), "Unexpected error parsing flags.")
+
+	assert.Equal(t, ErrorLevel, cfg.Level.Level(), "Expected flags to override the environment.")
+	assert.Equal(t, []string{"stdout", "stderr"}, cfg.OutputPaths, "Unexpected output paths.")
+	assert.True(t, cfg.DisableCaller, "Expected caller to be disabled.")
+	assert.Equal(t, &SamplingConfig{Initial: 100, Thereafter: 7}, cfg.Sampling, "Unexpected sampling.")
+	assert.Equal(t, 100, sampling.Thereafter, "Expected shared SamplingConfig to be unchanged.")
+	assert.Equal(t, DebugLevel, cfg.NamedLevels.Level("http"), "Unexpected named level.")
+	assert.Equal(t, "json", cfg.Encoding, "Expected unset flags to keep the current value.")
+
+	assert.Error(t, fs.Parse([]string{"-log-sampling=maybe"}), "Expected error parsing invalid bool.")
+	require.NoError(t, fs.Parse([]string{"-log-sampling=false"}), "Unexpected error parsing flags.")
+	assert.Nil(t, cfg.Sampling, "Expected sampling to be disabled.")
+
+	var usage bytes.Buffer
+	fs.SetOutput(&usage)
+	fs.PrintDefaults()
+	assert.Contains(t, usage.String(), "-log-level", "Expected level flag in usage.")
+	assert.Contains(t, usage.String(), "-log-sampling-initial", "Expected sampling flag in usage.")
+}
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"go.uber.org/zap/zapcore"
 )
@@ -37,6 +38,10 @@ import (
 // The GET request returns a JSON description of the current logging level like:
 //   {"level":"info"}
 //
+// While a temporary level is set, the description also includes when it
+// expires and the level that will be restored then:
+//   {"level":"debug","expires":"2021-06-02T15:04:05Z","restore":"info"}
+//
 // PUT
 //
 // The PUT request changes the logging level. It is perfectly safe to change the
@@ -67,28 +72,49 @@ import (
 //
 //    curl -X PUT localhost:8080/log/level -H "Content-Type: application/json" -d '{"level":"debug"}'
 //
+// With either content type, an optional duration sets the level temporarily;
+// the previous level is restored automatically once the duration elapses.
+// The duration is formatted as accepted by time.ParseDuration, like:
+//
+//    curl -X PUT localhost:8080/log/level -d level=debug -d duration=15m
+//    curl -X PUT localhost:8080/log/level -H "Content-Type: application/json" -d '{"level":"debug","duration":"15m"}'
+//
 func (lvl AtomicLevel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	type errorResponse struct {
 		Error string `json:"error"`
 	}
 	type payload struct {
-		Level zapcore.Level `json:"level"`
+		Level   zapcore.Level  `json:"level"`
+		Expires *time.Time     `json:"expires,omitempty"`
+		Restore *zapcore.Level `json:"restore,omitempty"`
+	}
+	describe := func() payload {
+		pld := payload{Level: lvl.Level()}
+		if restore, expires, ok := lvl.Override(); ok {
+			expires = expires.UTC()
+			pld.Expires, pld.Restore = &expires, &restore
+		}
+		return pld
 	}
 
 	enc := json.NewEncoder(w)
 
 	switch r.Method {
 	case http.MethodGet:
-		enc.Encode(payload{Level: lvl.Level()})
+		enc.Encode(describe())
 	case http.MethodPut:
-		requestedLvl, err := decodePutRequest(r.Header.Get("Content-Type"), r)
+		req, err := decodePutRequest(r.Header.Get("Content-Type"), r)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			enc.Encode(errorResponse{Error: err.Error()})
 			return
 		}
-		lvl.SetLevel(requestedLvl)
-		enc.Encode(payload{Level: lvl.Level()})
+		if req.duration > 0 {
+			lvl.SetLevelFor(req.level, req.duration)
+		} else {
+			lvl.SetLevel(req.level)
+		}
+		enc.Encode(describe())
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		enc.Encode(errorResponse{
@@ -97,36 +123,67 @@ func (lvl AtomicLevel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// putRequest is a decoded PUT request. A zero duration sets the level
+// permanently.
+type putRequest struct {
+	level    zapcore.Level
+	duration time.Duration
+}
+
 // Decodes incoming PUT requests and returns the requested logging level.
-func decodePutRequest(contentType string, r *http.Request) (zapcore.Level, error) {
+func decodePutRequest(contentType string, r *http.Request) (putRequest, error) {
 	if contentType == "application/x-www-form-urlencoded" {
 		return decodePutURL(r)
 	}
 	return decodePutJSON(r.Body)
 }
 
-func decodePutURL(r *http.Request) (zapcore.Level, error) {
+func decodePutURL(r *http.Request) (putRequest, error) {
 	lvl := r.FormValue("level")
 	if lvl == "" {
-		return 0, fmt.Errorf("must specify logging level")
+		return putRequest{}, fmt.Errorf("must specify logging level")
 	}
-	var l zapcore.Level
-	if err := l.UnmarshalText([]byte(lvl)); err != nil {
-		return 0, err
+	var req putRequest
+	if err := req.level.UnmarshalText([]byte(lvl)); err != nil {
+		return putRequest{}, err
 	}
-	return l, nil
+	if d := r.FormValue("duration"); d != "" {
+		var err error
+		if req.duration, err = parsePutDuration(d); err != nil {
+			return putRequest{}, err
+		}
+	}
+	return req, nil
 }
 
-func decodePutJSON(body io.Reader) (zapcore.Level, error) {
+func decodePutJSON(body io.Reader) (putRequest, error) {
 	var pld struct {
-		Level *zapcore.Level `json:"level"`
+		Level    *zapcore.Level `json:"level"`
+		Duration string         `json:"duration"`
 	}
 	if err := json.NewDecoder(body).Decode(&pld); err != nil {
-		return 0, fmt.Errorf("malformed request body: %v", err)
+		return putRequest{}, fmt.Errorf("malformed request body: %v", err)
 	}
 	if pld.Level == nil {
-		return 0, fmt.Errorf("must specify logging level")
+		return putRequest{}, fmt.Errorf("must specify logging level")
+	}
+	req := putRequest{level: *pld.Level}
+	if pld.Duration != "" {
+		var err error
+		if req.duration, err = parsePutDuration(pld.Duration); err != nil {
+			return putRequest{}, err
+		}
 	}
-	return *pld.Level, nil
+	return req, nil
+}
 
+func parsePutDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: %v", err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q: must be positive", s)
+	}
+	return d, nil
 }
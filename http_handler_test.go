@@ -26,6 +26,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -188,3 +189,86 @@ func TestAtomicLevelServeHTTP(t *testing.T) {
 		})
 	}
 }
+
+func TestAtomicLevelServeHTTPDuration(t *testing.T) {
+	tests := []struct {
+		desc        string
+		contentType string
+		body        string
+	}{
+		{
+			desc: "JSON",
+			body: `{"level":"debug","duration":"1h"}`,
+		},
+		{
+			desc:        "URL encoded",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "level=debug&duration=1h",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			lvl := zap.NewAtomicLevelAt(zap.WarnLevel)
+			server := httptest.NewServer(lvl)
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodPut, server.URL, strings.NewReader(tt.body))
+			require.NoError(t, err, "Error constructing request.")
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err, "Error making request.")
+			res.Body.Close()
+			require.Equal(t, http.StatusOK, res.StatusCode, "Unexpected status code.")
+			assert.Equal(t, zap.DebugLevel, lvl.Level(), "Expected temporary level.")
+
+			res, err = http.Get(server.URL)
+			require.NoError(t, err, "Error making GET request.")
+			defer res.Body.Close()
+
+			var pld struct {
+				Level   zapcore.Level `json:"level"`
+				Expires time.Time     `json:"expires"`
+				Restore zapcore.Level `json:"restore"`
+			}
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&pld), "Decoding response body")
+			assert.Equal(t, zap.DebugLevel, pld.Level, "Unexpected logging level returned")
+			assert.Equal(t, zap.WarnLevel, pld.Restore, "Unexpected level to restore")
+			assert.WithinDuration(t, time.Now().Add(time.Hour), pld.Expires, time.Minute, "Unexpected expiry")
+		})
+	}
+}
+
+func TestAtomicLevelServeHTTPDurationErrors(t *testing.T) {
+	tests := []struct {
+		desc        string
+		contentType string
+		body        string
+	}{
+		{desc: "JSON malformed", body: `{"level":"debug","duration":"soon"}`},
+		{desc: "JSON negative", body: `{"level":"debug","duration":"-1m"}`},
+		{desc: "URL encoded malformed", contentType: "application/x-www-form-urlencoded", body: "level=debug&duration=soon"},
+		{desc: "URL encoded zero", contentType: "application/x-www-form-urlencoded", body: "level=debug&duration=0s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			lvl := zap.NewAtomicLevelAt(zap.WarnLevel)
+			server := httptest.NewServer(lvl)
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodPut, server.URL, strings.NewReader(tt.body))
+			require.NoError(t, err, "Error constructing request.")
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err, "Error making request.")
+			res.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Unexpected status code.")
+			assert.Equal(t, zap.WarnLevel, lvl.Level(), "Unexpected level change.")
+		})
+	}
+}
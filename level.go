@@ -21,6 +21,9 @@
 package zap
 
 import (
+	"sync"
+	"time"
+
 	"go.uber.org/atomic"
 	"go.uber.org/zap/zapcore"
 )
@@ -68,6 +71,54 @@ func (f LevelEnablerFunc) Enabled(lvl zapcore.Level) bool { return f(lvl) }
 // their internal atomic pointer.
 type AtomicLevel struct {
 	l *atomic.Int32
+	s *atomicLevelState
+}
+
+// atomicLevelState tracks a temporary level set with SetLevelFor, and the
+// subscribers to level changes.
+type atomicLevelState struct {
+	mu      sync.Mutex
+	timer   *time.Timer // nil unless a temporary level is set
+	restore zapcore.Level
+	expires time.Time
+	subs    []*levelSubscription // copied on write
+	aliases map[string]zapcore.Level
+
+	// Changes are numbered while holding mu, and subscribers are notified of
+	// them in that order.
+	nextChange uint64
+	notifyMu   sync.Mutex
+	notifyCond *sync.Cond // on notifyMu
+	notified   uint64
+}
+
+// setAliases makes the level accept the values enc serializes levels to.
+func (lvl AtomicLevel) setAliases(enc zapcore.LevelEncoder) {
+	aliases := make(map[string]zapcore.Level)
+	for l, alias := range zapcore.LevelEncoderAliases(enc) {
+		aliases[alias] = l
+	}
+	st := lvl.s
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.aliases = aliases
+}
+
+func (st *atomicLevelState) lookupAlias(alias string) (zapcore.Level, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	l, ok := st.aliases[alias]
+	return l, ok
+}
+
+func newAtomicLevelState() *atomicLevelState {
+	st := &atomicLevelState{}
+	st.notifyCond = sync.NewCond(&st.notifyMu)
+	return st
+}
+
+type levelSubscription struct {
+	fn func(old, new zapcore.Level)
 }
 
 // NewAtomicLevel creates an AtomicLevel with InfoLevel and above logging
@@ -75,6 +126,7 @@ type AtomicLevel struct {
 func NewAtomicLevel() AtomicLevel {
 	return AtomicLevel{
 		l: atomic.NewInt32(int32(InfoLevel)),
+		s: newAtomicLevelState(),
 	}
 }
 
@@ -97,9 +149,140 @@ func (lvl AtomicLevel) Level() zapcore.Level {
 	return zapcore.Level(int8(lvl.l.Load()))
 }
 
-// SetLevel alters the logging level.
+// SetLevel alters the logging level. It cancels any pending restore of a
+// temporary level set with SetLevelFor.
 func (lvl AtomicLevel) SetLevel(l zapcore.Level) {
-	lvl.l.Store(int32(l))
+	st := lvl.s
+	if st == nil {
+		lvl.l.Store(int32(l))
+		return
+	}
+
+	st.mu.Lock()
+	st.stopLocked()
+	notify := lvl.storeLocked(l)
+	st.mu.Unlock()
+	notify()
+}
+
+// SetLevelFor alters the logging level for the duration d, then restores the
+// level it replaced. Setting another temporary level before then extends or
+// shortens the override, but still restores the original level; SetLevel
+// cancels the restore.
+func (lvl AtomicLevel) SetLevelFor(l zapcore.Level, d time.Duration) {
+	st := lvl.s
+	st.mu.Lock()
+
+	restore := lvl.Level()
+	if st.timer != nil {
+		restore = st.restore
+		st.stopLocked()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		st.mu.Lock()
+		// Ignore timers that were replaced or stopped too late.
+		if st.timer != timer {
+			st.mu.Unlock()
+			return
+		}
+		st.stopLocked()
+		notify := lvl.storeLocked(st.restore)
+		st.mu.Unlock()
+		notify()
+	})
+	st.timer = timer
+	st.restore = restore
+	st.expires = time.Now().Add(d)
+	notify := lvl.storeLocked(l)
+	st.mu.Unlock()
+	notify()
+}
+
+// Subscribe registers fn to be called with the old and new levels whenever
+// the level changes, whether by SetLevel, SetLevelFor, the restore of a
+// temporary level, or UnmarshalText. Setting the level it already has isn't
+// a change. Since AtomicLevels are shared by copying, fn sees changes made
+// through any copy, as do all loggers using the level.
+//
+// fn is called synchronously by the goroutine that changed the level, after
+// the change, and calls are ordered like the changes. fn must not change the
+// level itself. Call the returned function to unsubscribe.
+func (lvl AtomicLevel) Subscribe(fn func(old, new zapcore.Level)) (unsubscribe func()) {
+	st := lvl.s
+	sub := &levelSubscription{fn: fn}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.subs = append(st.subs[:len(st.subs):len(st.subs)], sub)
+
+	return func() {
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		for i, s := range st.subs {
+			if s == sub {
+				subs := make([]*levelSubscription, 0, len(st.subs)-1)
+				subs = append(subs, st.subs[:i]...)
+				st.subs = append(subs, st.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// storeLocked sets the level while holding st.mu, and returns a function to
+// notify subscribers of the change once st.mu is released.
+func (lvl AtomicLevel) storeLocked(l zapcore.Level) (notify func()) {
+	st := lvl.s
+	old := zapcore.Level(lvl.l.Swap(int32(l)))
+	if old == l || len(st.subs) == 0 {
+		return func() {}
+	}
+
+	subs := st.subs
+	change := st.nextChange
+	st.nextChange++
+	return func() {
+		st.notifyMu.Lock()
+		for st.notified != change {
+			st.notifyCond.Wait()
+		}
+		st.notifyMu.Unlock()
+
+		defer func() {
+			st.notifyMu.Lock()
+			st.notified++
+			st.notifyMu.Unlock()
+			st.notifyCond.Broadcast()
+		}()
+		for _, sub := range subs {
+			sub.fn(old, l)
+		}
+	}
+}
+
+// Override reports the pending restore of a temporary level set with
+// SetLevelFor: the level to restore, and when. It returns false if there's
+// none.
+func (lvl AtomicLevel) Override() (restore zapcore.Level, expires time.Time, ok bool) {
+	st := lvl.s
+	if st == nil {
+		return 0, time.Time{}, false
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.timer == nil {
+		return 0, time.Time{}, false
+	}
+	return st.restore, st.expires, true
+}
+
+func (st *atomicLevelState) stopLocked() {
+	if st.timer != nil {
+		st.timer.Stop()
+		st.timer = nil
+	}
 }
 
 // String returns the string representation of the underlying Level.
@@ -109,15 +292,24 @@ func (lvl AtomicLevel) String() string {
 
 // UnmarshalText unmarshals the text to an AtomicLevel. It uses the same text
 // representations as the static zapcore.Levels ("debug", "info", "warn",
-// "error", "dpanic", "panic", and "fatal").
+// "error", "dpanic", "panic", and "fatal"). A level unmarshaled as part of a
+// Config also accepts the values its levelEncoder mapping serializes levels
+// to.
 func (lvl *AtomicLevel) UnmarshalText(text []byte) error {
 	if lvl.l == nil {
 		lvl.l = &atomic.Int32{}
 	}
+	if lvl.s == nil {
+		lvl.s = newAtomicLevelState()
+	}
 
 	var l zapcore.Level
 	if err := l.UnmarshalText(text); err != nil {
-		return err
+		alias, ok := lvl.s.lookupAlias(string(text))
+		if !ok {
+			return err
+		}
+		l = alias
 	}
 
 	lvl.SetLevel(l)
@@ -0,0 +1,453 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/atomic"
+	"go.uber.org/zap/zapcore"
+)
+
+// _maxCachedLevelNames bounds the number of logger names whose effective
+// levels a LevelRegistry remembers, and the number of names it lists.
+const _maxCachedLevelNames = 4096
+
+// A LevelRule sets the level of the loggers whose names match Pattern.
+type LevelRule struct {
+	Pattern string
+	Level   zapcore.Level
+}
+
+// A LevelRegistry sets logging levels per logger name, as built by
+// Logger.Named, using rules that can be changed at runtime. Changes take
+// effect immediately for all loggers, including child loggers that were
+// created earlier.
+//
+// A rule's pattern is a dotted logger name, like "http.client", and each of
+// its dot-separated parts may be a glob in the syntax of path.Match, like
+// "db.*". A rule applies to the loggers whose names match the pattern and to
+// all of their descendants, so "db.*" covers "db.pool" and "db.pool.conn",
+// but not "db". When several rules apply, the one matching the most name
+// parts wins; among those, rules without globs beat rules with globs, and
+// later rules beat earlier ones. Loggers without any applicable rule,
+// including unnamed loggers, use the registry's root level.
+//
+// A LevelRegistry is a zapcore.LevelEnabler that enables the lowest level any
+// rule or the root level enables, so it can be used as the level of the
+// Core it filters. Install it on a Logger with the NamedLevels option, or set
+// Config.NamedLevels.
+type LevelRegistry struct {
+	root atomic.Value // AtomicLevel
+
+	// minRule is the lowest level enabled by any rule, if hasRules.
+	hasRules atomic.Bool
+	minRule  atomic.Int32
+
+	mu    sync.RWMutex
+	rules []levelRule
+	cache map[string]cachedLevel
+
+	names    sync.Map // logger names seen by Core, to struct{}
+	numNames atomic.Int32
+}
+
+type levelRule struct {
+	LevelRule
+
+	parts   []string
+	literal bool
+}
+
+type cachedLevel struct {
+	level zapcore.Level
+	ok    bool // false if no rule applies
+}
+
+// NewLevelRegistry creates a LevelRegistry without rules. Loggers use the
+// root level until rules are added.
+func NewLevelRegistry(root AtomicLevel) *LevelRegistry {
+	r := &LevelRegistry{}
+	r.root.Store(root)
+	return r
+}
+
+// Root returns the level used by loggers without any applicable rule. A
+// LevelRegistry unmarshaled from a Config uses the Config's Level.
+func (r *LevelRegistry) Root() AtomicLevel {
+	root, _ := r.root.Load().(AtomicLevel)
+	return root
+}
+
+// bindRoot sets the root level unless the registry already has one.
+func (r *LevelRegistry) bindRoot(root AtomicLevel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Root() == (AtomicLevel{}) {
+		r.root.Store(root)
+	}
+}
+
+func (r *LevelRegistry) rootLevel() zapcore.Level {
+	root := r.Root()
+	if root == (AtomicLevel{}) {
+		return InfoLevel
+	}
+	return root.Level()
+}
+
+// SetLevel adds a rule setting the level of loggers matching pattern, or
+// replaces the rule with the same pattern.
+func (r *LevelRegistry) SetLevel(pattern string, lvl zapcore.Level) error {
+	rule, err := newLevelRule(pattern, lvl)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rules := make([]levelRule, 0, len(r.rules)+1)
+	for _, old := range r.rules {
+		if old.Pattern != rule.Pattern {
+			rules = append(rules, old)
+		}
+	}
+	r.setRulesLocked(append(rules, rule))
+	return nil
+}
+
+// UnsetLevel removes the rule with the given pattern, reporting whether
+// there was one.
+func (r *LevelRegistry) UnsetLevel(pattern string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, rule := range r.rules {
+		if rule.Pattern == pattern {
+			rules := make([]levelRule, 0, len(r.rules)-1)
+			rules = append(rules, r.rules[:i]...)
+			r.setRulesLocked(append(rules, r.rules[i+1:]...))
+			return true
+		}
+	}
+	return false
+}
+
+// SetRules replaces all rules at once. Later rules beat earlier ones when
+// they're equally specific.
+func (r *LevelRegistry) SetRules(rules ...LevelRule) error {
+	// Like SetLevel, a repeated pattern replaces the earlier rule.
+	last := make(map[string]int, len(rules))
+	for i, lr := range rules {
+		last[lr.Pattern] = i
+	}
+	parsed := make([]levelRule, 0, len(last))
+	for i, lr := range rules {
+		if last[lr.Pattern] != i {
+			continue
+		}
+		rule, err := newLevelRule(lr.Pattern, lr.Level)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, rule)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setRulesLocked(parsed)
+	return nil
+}
+
+// Rules returns the current rules, in the order they were added.
+func (r *LevelRegistry) Rules() []LevelRule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rules := make([]LevelRule, len(r.rules))
+	for i, rule := range r.rules {
+		rules[i] = rule.LevelRule
+	}
+	return rules
+}
+
+func (r *LevelRegistry) setRulesLocked(rules []levelRule) {
+	r.rules = rules
+	r.cache = nil
+
+	if len(rules) == 0 {
+		r.hasRules.Store(false)
+		return
+	}
+	min := rules[0].Level
+	for _, rule := range rules[1:] {
+		if rule.Level < min {
+			min = rule.Level
+		}
+	}
+	r.minRule.Store(int32(min))
+	r.hasRules.Store(true)
+}
+
+// Level returns the effective level of the logger with the given name.
+func (r *LevelRegistry) Level(name string) zapcore.Level {
+	if lvl, ok := r.ruleLevel(name); ok {
+		return lvl
+	}
+	return r.rootLevel()
+}
+
+// ruleLevel returns the level set by the rule that applies to name, if any.
+func (r *LevelRegistry) ruleLevel(name string) (zapcore.Level, bool) {
+	if !r.hasRules.Load() {
+		return 0, false
+	}
+
+	r.mu.RLock()
+	c, cached := r.cache[name]
+	if !cached {
+		c = r.matchLocked(name)
+	}
+	r.mu.RUnlock()
+
+	if !cached {
+		r.mu.Lock()
+		if r.cache == nil {
+			r.cache = make(map[string]cachedLevel)
+		}
+		if len(r.cache) < _maxCachedLevelNames {
+			// Rules may have changed since we matched; match again.
+			r.cache[name] = r.matchLocked(name)
+		}
+		r.mu.Unlock()
+	}
+	return c.level, c.ok
+}
+
+func (r *LevelRegistry) matchLocked(name string) cachedLevel {
+	if name == "" {
+		return cachedLevel{}
+	}
+	parts := strings.Split(name, ".")
+
+	var best *levelRule
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if !rule.matches(parts) {
+			continue
+		}
+		if best == nil || len(rule.parts) > len(best.parts) ||
+			len(rule.parts) == len(best.parts) && (rule.literal || !best.literal) {
+			best = rule
+		}
+	}
+	if best == nil {
+		return cachedLevel{}
+	}
+	return cachedLevel{level: best.Level, ok: true}
+}
+
+// Names returns the sorted names of the loggers whose entries have reached a
+// Core wrapped by the registry, whether or not they were written. Unnamed
+// loggers aren't listed.
+func (r *LevelRegistry) Names() []string {
+	var names []string
+	r.names.Range(func(name, _ interface{}) bool {
+		names = append(names, name.(string))
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+func (r *LevelRegistry) observe(name string) {
+	if name == "" {
+		return
+	}
+	if _, ok := r.names.Load(name); ok || r.numNames.Load() >= _maxCachedLevelNames {
+		return
+	}
+	if _, loaded := r.names.LoadOrStore(name, struct{}{}); !loaded {
+		r.numNames.Inc()
+	}
+}
+
+// Enabled implements zapcore.LevelEnabler. It enables the levels enabled by
+// the root level or by any rule.
+func (r *LevelRegistry) Enabled(lvl zapcore.Level) bool {
+	if r.hasRules.Load() && zapcore.Level(r.minRule.Load()).Enabled(lvl) {
+		return true
+	}
+	return r.rootLevel().Enabled(lvl)
+}
+
+// Core wraps core so that it drops entries below the level of their logger's
+// name. core itself must enable the levels the registry enables; using the
+// registry as core's LevelEnabler is simplest.
+func (r *LevelRegistry) Core(core zapcore.Core) zapcore.Core {
+	return &namedLevelCore{Core: core, reg: r}
+}
+
+// String returns the rules in the form accepted by UnmarshalText, like
+// "db.*=debug,http.client=warn".
+func (r *LevelRegistry) String() string {
+	rules := r.Rules()
+	specs := make([]string, len(rules))
+	for i, rule := range rules {
+		specs[i] = rule.Pattern + "=" + rule.Level.String()
+	}
+	return strings.Join(specs, ",")
+}
+
+// MarshalText marshals the rules in the form returned by String.
+func (r *LevelRegistry) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText replaces the rules with comma-separated pattern=level pairs,
+// like "db.*=debug,http.client=warn".
+func (r *LevelRegistry) UnmarshalText(text []byte) error {
+	var rules []LevelRule
+	for _, spec := range strings.Split(string(text), ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		i := strings.LastIndexByte(spec, '=')
+		if i < 0 {
+			return fmt.Errorf("invalid level rule %q: expected pattern=level", spec)
+		}
+		var lvl zapcore.Level
+		if err := lvl.UnmarshalText([]byte(strings.TrimSpace(spec[i+1:]))); err != nil {
+			return fmt.Errorf("invalid level rule %q: %v", spec, err)
+		}
+		rules = append(rules, LevelRule{Pattern: strings.TrimSpace(spec[:i]), Level: lvl})
+	}
+	return r.SetRules(rules...)
+}
+
+// UnmarshalYAML replaces the rules with those in a map from patterns to
+// levels. Since maps are unordered, rules are added in pattern order.
+//
+//	namedLevels:
+//	  db.*: debug
+//	  http.client: warn
+//
+// If value is string, it uses UnmarshalText.
+//
+//	namedLevels: db.*=debug,http.client=warn
+func (r *LevelRegistry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var m map[string]string
+	if err := unmarshal(&m); err == nil {
+		patterns := make([]string, 0, len(m))
+		for p := range m {
+			patterns = append(patterns, p)
+		}
+		sort.Strings(patterns)
+
+		rules := make([]LevelRule, len(patterns))
+		for i, p := range patterns {
+			if err := rules[i].Level.UnmarshalText([]byte(m[p])); err != nil {
+				return fmt.Errorf("invalid level for %q: %v", p, err)
+			}
+			rules[i].Pattern = p
+		}
+		return r.SetRules(rules...)
+	}
+
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return r.UnmarshalText([]byte(s))
+}
+
+// UnmarshalJSON unmarshals JSON to a LevelRegistry as same way UnmarshalYAML
+// does.
+func (r *LevelRegistry) UnmarshalJSON(data []byte) error {
+	return r.UnmarshalYAML(func(v interface{}) error {
+		return json.Unmarshal(data, v)
+	})
+}
+
+func newLevelRule(pattern string, lvl zapcore.Level) (levelRule, error) {
+	if pattern == "" {
+		return levelRule{}, errors.New("empty level rule pattern: set the root level instead")
+	}
+	rule := levelRule{
+		LevelRule: LevelRule{Pattern: pattern, Level: lvl},
+		parts:     strings.Split(pattern, "."),
+		literal:   true,
+	}
+	for _, part := range rule.parts {
+		if part == "" {
+			return levelRule{}, fmt.Errorf("invalid level rule pattern %q: empty name part", pattern)
+		}
+		if _, err := path.Match(part, ""); err != nil {
+			return levelRule{}, fmt.Errorf("invalid level rule pattern %q: %v", pattern, err)
+		}
+		if strings.ContainsAny(part, `*?[\`) {
+			rule.literal = false
+		}
+	}
+	return rule, nil
+}
+
+// matches reports whether the rule applies to the logger name with the
+// given parts, either directly or through one of its ancestors.
+func (rule *levelRule) matches(name []string) bool {
+	if len(rule.parts) > len(name) {
+		return false
+	}
+	for i, part := range rule.parts {
+		if ok, _ := path.Match(part, name[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// namedLevelCore drops entries below the level of their logger's name.
+type namedLevelCore struct {
+	zapcore.Core
+
+	reg *LevelRegistry
+}
+
+func (c *namedLevelCore) Enabled(lvl zapcore.Level) bool {
+	return c.reg.Enabled(lvl) && c.Core.Enabled(lvl)
+}
+
+func (c *namedLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &namedLevelCore{Core: c.Core.With(fields), reg: c.reg}
+}
+
+func (c *namedLevelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	c.reg.observe(ent.LoggerName)
+	if !c.reg.Level(ent.LoggerName).Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
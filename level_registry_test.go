@@ -0,0 +1,183 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zap
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestLevelRegistryMatching(t *testing.T) {
+	reg := NewLevelRegistry(NewAtomicLevelAt(InfoLevel))
+	require.NoError(t, reg.SetRules(
+		LevelRule{"db.*", DebugLevel},
+		LevelRule{"db.pool", WarnLevel},
+		LevelRule{"http.client", ErrorLevel},
+		LevelRule{"*.client", DPanicLevel},
+		LevelRule{"grpc", WarnLevel},
+		LevelRule{"grpc", DebugLevel},
+	), "Unexpected error setting rules.")
+
+	tests := []struct {
+		name string
+		want zapcore.Level
+	}{
+		{"", InfoLevel},
+		{"other", InfoLevel},
+		{"db", InfoLevel},
+		{"db.query", DebugLevel},
+		{"db.query.slow", DebugLevel},
+		{"db.pool", WarnLevel},
+		{"db.pool.conn", WarnLevel},
+		{"http", InfoLevel},
+		{"http.client", ErrorLevel},
+		{"http.client.retry", ErrorLevel},
+		{"rpc.client", DPanicLevel},
+		{"grpc.server", DebugLevel},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, reg.Level(tt.name), "Unexpected level for %q.", tt.name)
+	}
+
+	assert.Equal(t, "db.*=debug,db.pool=warn,http.client=error,*.client=dpanic,grpc=debug", reg.String(), "Unexpected rules.")
+}
+
+func TestLevelRegistryRuntimeChanges(t *testing.T) {
+	root := NewAtomicLevelAt(InfoLevel)
+	reg := NewLevelRegistry(root)
+	fac, logs := observer.New(reg)
+	logger := New(fac, NamedLevels(reg))
+
+	db := logger.Named("db")
+	pool := db.Named("pool")
+	other := logger.Named("other")
+
+	pool.Debug("hidden")
+	assert.Equal(t, 0, logs.Len(), "Unexpected debug log before any rules.")
+
+	require.NoError(t, reg.SetLevel("db", DebugLevel), "Unexpected error setting level.")
+	assert.True(t, reg.Enabled(DebugLevel), "Expected registry to enable levels enabled by rules.")
+	pool.Debug("shown")
+	other.Debug("hidden")
+	db.Debug("shown")
+	assert.Equal(t, []string{"shown", "shown"}, messages(logs.TakeAll()), "Expected debug logs only under db.")
+
+	require.NoError(t, reg.SetLevel("db.pool", ErrorLevel), "Unexpected error setting level.")
+	pool.Warn("hidden")
+	db.Debug("shown")
+	assert.Equal(t, []string{"shown"}, messages(logs.TakeAll()), "Expected more specific rule to win.")
+
+	root.SetLevel(ErrorLevel)
+	other.Warn("hidden")
+	db.Warn("shown")
+	assert.Equal(t, []string{"shown"}, messages(logs.TakeAll()), "Expected root level changes to apply.")
+
+	assert.True(t, reg.UnsetLevel("db"), "Expected to remove rule.")
+	assert.False(t, reg.UnsetLevel("db"), "Unexpected second removal.")
+	db.Warn("hidden")
+	assert.Equal(t, 0, logs.Len(), "Expected root level after removing rule.")
+	assert.Equal(t, []LevelRule{{"db.pool", ErrorLevel}}, reg.Rules(), "Unexpected remaining rules.")
+	assert.False(t, reg.Enabled(WarnLevel), "Unexpected enabled level.")
+	assert.Equal(t, []string{"db", "db.pool", "other"}, reg.Names(), "Unexpected logger names.")
+}
+
+func messages(entries []observer.LoggedEntry) []string {
+	msgs := make([]string, len(entries))
+	for i, e := range entries {
+		msgs[i] = e.Message
+	}
+	return msgs
+}
+
+func TestLevelRegistryInvalidRules(t *testing.T) {
+	reg := NewLevelRegistry(NewAtomicLevel())
+	for _, pattern := range []string{"", "db.", ".db", "db..pool", "db.[a"} {
+		assert.Error(t, reg.SetLevel(pattern, DebugLevel), "Expected error for pattern %q.", pattern)
+	}
+	assert.Error(t, reg.UnmarshalText([]byte("db")), "Expected error for rule without a level.")
+	assert.Error(t, reg.UnmarshalText([]byte("db=loud")), "Expected error for invalid level.")
+	assert.Empty(t, reg.Rules(), "Unexpected rules after errors.")
+}
+
+func TestLevelRegistryUnmarshal(t *testing.T) {
+	want := []LevelRule{{"db.*", DebugLevel}, {"http.client", WarnLevel}}
+
+	tests := []struct {
+		desc      string
+		unmarshal func(*LevelRegistry) error
+	}{
+		{"text", func(r *LevelRegistry) error {
+			return r.UnmarshalText([]byte(" db.*=debug, http.client=warn ,"))
+		}},
+		{"json string", func(r *LevelRegistry) error {
+			return json.Unmarshal([]byte(`"db.*=debug,http.client=warn"`), r)
+		}},
+		{"json object", func(r *LevelRegistry) error {
+			return json.Unmarshal([]byte(`{"http.client": "warn", "db.*": "debug"}`), r)
+		}},
+		{"yaml string", func(r *LevelRegistry) error {
+			return yaml.Unmarshal([]byte(`db.*=debug,http.client=warn`), r)
+		}},
+		{"yaml map", func(r *LevelRegistry) error {
+			return yaml.Unmarshal([]byte("http.client: warn\ndb.*: debug"), r)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var reg LevelRegistry
+			require.NoError(t, tt.unmarshal(&reg), "Unexpected error unmarshaling.")
+			assert.Equal(t, want, reg.Rules(), "Unexpected rules.")
+			assert.Equal(t, InfoLevel, reg.Level("other"), "Expected InfoLevel without a root level.")
+
+			text, err := reg.MarshalText()
+			require.NoError(t, err, "Unexpected error marshaling.")
+			assert.Equal(t, "db.*=debug,http.client=warn", string(text), "Unexpected text.")
+		})
+	}
+}
+
+func TestConfigNamedLevels(t *testing.T) {
+	var cfg Config
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"level": "warn",
+		"namedLevels": {"db": "debug"},
+		"encoding": "json",
+		"outputPaths": ["stdout"]
+	}`), &cfg), "Unexpected error unmarshaling config.")
+	require.NotNil(t, cfg.NamedLevels, "Expected named levels.")
+
+	logger, err := cfg.Build()
+	require.NoError(t, err, "Unexpected error building logger.")
+	assert.NotNil(t, logger.Named("db").Check(DebugLevel, ""), "Expected debug logs for db.")
+	assert.Nil(t, logger.Named("http").Check(InfoLevel, ""), "Unexpected info logs for http.")
+	assert.NotNil(t, logger.Check(WarnLevel, ""), "Expected root level for unnamed logger.")
+
+	cfg.NamedLevels.SetLevel("http", InfoLevel)
+	assert.NotNil(t, logger.Named("http").Check(InfoLevel, ""), "Expected runtime change to apply.")
+	assert.Equal(t, cfg.Level, cfg.NamedLevels.Root(), "Expected Config.Level as the root level.")
+}
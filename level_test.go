@@ -23,10 +23,12 @@ package zap
 import (
 	"sync"
 	"testing"
+	"time"
 
 	"go.uber.org/zap/zapcore"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLevelEnablerFunc(t *testing.T) {
@@ -75,6 +77,136 @@ func TestAtomicLevelMutation(t *testing.T) {
 	wg.Wait()
 }
 
+func waitForLevel(t testing.TB, lvl AtomicLevel, want zapcore.Level) {
+	deadline := time.Now().Add(time.Second)
+	for lvl.Level() != want && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, want, lvl.Level(), "Timed out waiting for level.")
+}
+
+func TestAtomicLevelSetLevelFor(t *testing.T) {
+	t.Run("restores", func(t *testing.T) {
+		lvl := NewAtomicLevelAt(WarnLevel)
+		lvl.SetLevelFor(DebugLevel, 10*time.Millisecond)
+		assert.Equal(t, DebugLevel, lvl.Level(), "Expected temporary level.")
+
+		restore, expires, ok := lvl.Override()
+		require.True(t, ok, "Expected a pending restore.")
+		assert.Equal(t, WarnLevel, restore, "Unexpected level to restore.")
+		assert.WithinDuration(t, time.Now().Add(10*time.Millisecond), expires, time.Second, "Unexpected expiry.")
+
+		waitForLevel(t, lvl, WarnLevel)
+		_, _, ok = lvl.Override()
+		assert.False(t, ok, "Unexpected pending restore after expiry.")
+	})
+
+	t.Run("repeated overrides restore the original level", func(t *testing.T) {
+		lvl := NewAtomicLevelAt(WarnLevel)
+		lvl.SetLevelFor(InfoLevel, time.Hour)
+		lvl.SetLevelFor(DebugLevel, 10*time.Millisecond)
+		restore, _, ok := lvl.Override()
+		require.True(t, ok, "Expected a pending restore.")
+		assert.Equal(t, WarnLevel, restore, "Unexpected level to restore.")
+		waitForLevel(t, lvl, WarnLevel)
+	})
+
+	t.Run("SetLevel cancels", func(t *testing.T) {
+		lvl := NewAtomicLevelAt(WarnLevel)
+		lvl.SetLevelFor(DebugLevel, 10*time.Millisecond)
+		lvl.SetLevel(ErrorLevel)
+		_, _, ok := lvl.Override()
+		assert.False(t, ok, "Unexpected pending restore after SetLevel.")
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, ErrorLevel, lvl.Level(), "Unexpected restore after SetLevel.")
+	})
+
+	t.Run("unmarshaled", func(t *testing.T) {
+		var lvl AtomicLevel
+		require.NoError(t, lvl.UnmarshalText([]byte("error")), "Unexpected error unmarshaling.")
+		lvl.SetLevelFor(DebugLevel, 10*time.Millisecond)
+		waitForLevel(t, lvl, ErrorLevel)
+	})
+}
+
+func TestAtomicLevelSubscribe(t *testing.T) {
+	type change struct{ old, new zapcore.Level }
+
+	lvl := NewAtomicLevelAt(InfoLevel)
+	changes := make(chan change, 10)
+	unsubscribe := lvl.Subscribe(func(old, new zapcore.Level) {
+		changes <- change{old, new}
+	})
+	others := make(chan change, 10)
+	copied := lvl
+	defer copied.Subscribe(func(old, new zapcore.Level) {
+		others <- change{old, new}
+	})()
+
+	lvl.SetLevel(WarnLevel)
+	lvl.SetLevel(WarnLevel) // not a change
+	copied.SetLevel(ErrorLevel)
+	require.NoError(t, lvl.UnmarshalText([]byte("debug")), "Unexpected error unmarshaling.")
+	lvl.SetLevelFor(InfoLevel, 10*time.Millisecond)
+
+	receive := func(ch <-chan change, n int) []change {
+		var got []change
+		for i := 0; i < n; i++ {
+			select {
+			case c := <-ch:
+				got = append(got, c)
+			case <-time.After(time.Second):
+				t.Fatalf("Timed out waiting for change %d.", i)
+			}
+		}
+		return got
+	}
+	want := []change{
+		{InfoLevel, WarnLevel},
+		{WarnLevel, ErrorLevel},
+		{ErrorLevel, DebugLevel},
+		{DebugLevel, InfoLevel},
+		{InfoLevel, DebugLevel},
+	}
+	assert.Equal(t, want, receive(changes, 5), "Unexpected changes.")
+	assert.Equal(t, want, receive(others, 5), "Expected subscribers through copies to see all changes.")
+
+	unsubscribe()
+	unsubscribe()
+	lvl.SetLevel(FatalLevel)
+	assert.Equal(t, []change{{DebugLevel, FatalLevel}}, receive(others, 1), "Expected other subscribers to remain.")
+	assert.Len(t, changes, 0, "Unexpected change after unsubscribing.")
+}
+
+func TestAtomicLevelSubscribeOrdering(t *testing.T) {
+	lvl := NewAtomicLevel()
+	var last zapcore.Level
+	var outOfOrder bool
+	lvl.Subscribe(func(old, new zapcore.Level) {
+		// Calls are serialized, so no locking is needed.
+		if old != last {
+			outOfOrder = true
+		}
+		assert.Equal(t, new, lvl.Level(), "Expected to see the new level.")
+		_, _, _ = lvl.Override()
+		last = new
+	})
+	last = lvl.Level()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				lvl.SetLevel(zapcore.Level(j%3 + i%2))
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.False(t, outOfOrder, "Expected each change to start from the previous change's new level.")
+}
+
 func TestAtomicLevelText(t *testing.T) {
 	tests := []struct {
 		text   string
@@ -115,3 +247,11 @@ func TestAtomicLevelText(t *testing.T) {
 		}
 	}
 }
+
+func TestAtomicLevelTextAlias(t *testing.T) {
+	assert.NoError(t, zapcore.RegisterLevelAlias("test-atomic-warning", WarnLevel), "Unexpected error registering alias.")
+
+	var lvl AtomicLevel
+	assert.NoError(t, lvl.UnmarshalText([]byte("test-atomic-warning")), "Expected unmarshaling an alias to succeed.")
+	assert.Equal(t, WarnLevel, lvl.Level(), "Unexpected level after unmarshaling alias.")
+}
@@ -21,6 +21,7 @@
 package zap
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -42,12 +43,23 @@ type Logger struct {
 
 	development bool
 	addCaller   bool
-	onFatal     zapcore.CheckWriteAction // default is WriteThenFatal
+	onFatal     zapcore.CheckWriteHook // default is WriteThenFatal
+	onPanic     zapcore.CheckWriteHook // default is WriteThenPanic
 
 	name        string
 	errorOutput zapcore.WriteSyncer
 
-	addStack zapcore.LevelEnabler
+	addStack  zapcore.LevelEnabler
+	stackOpts stacktraceOptions
+
+	dumpGoroutines zapcore.LevelEnabler
+	dumpPath       string // empty to attach dumps to entries
+
+	ctxExtractors []func(context.Context) []Field
+	extractTrace  func(context.Context) zapcore.TraceContext
+
+	recoverLevel   zapcore.Level // default is ErrorLevel
+	recoverRepanic bool
 
 	callerSkip int
 
@@ -73,6 +85,9 @@ func New(core zapcore.Core, options ...Option) *Logger {
 		errorOutput: zapcore.Lock(os.Stderr),
 		addStack:    zapcore.FatalLevel + 1,
 		clock:       zapcore.DefaultClock,
+
+		dumpGoroutines: zapcore.FatalLevel + 1,
+		recoverLevel:   zapcore.ErrorLevel,
 	}
 	return log.WithOptions(options...)
 }
@@ -88,6 +103,9 @@ func NewNop() *Logger {
 		errorOutput: zapcore.AddSync(ioutil.Discard),
 		addStack:    zapcore.FatalLevel + 1,
 		clock:       zapcore.DefaultClock,
+
+		dumpGoroutines: zapcore.FatalLevel + 1,
+		recoverLevel:   zapcore.ErrorLevel,
 	}
 }
 
@@ -174,13 +192,13 @@ func (log *Logger) With(fields ...Field) *Logger {
 // is enabled. It's a completely optional optimization; in high-performance
 // applications, Check can help avoid allocating a slice to hold fields.
 func (log *Logger) Check(lvl zapcore.Level, msg string) *zapcore.CheckedEntry {
-	return log.check(lvl, msg)
+	return log.check(lvl, msg, nil)
 }
 
 // Debug logs a message at DebugLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (log *Logger) Debug(msg string, fields ...Field) {
-	if ce := log.check(DebugLevel, msg); ce != nil {
+	if ce := log.check(DebugLevel, msg, nil); ce != nil {
 		ce.Write(fields...)
 	}
 }
@@ -188,7 +206,7 @@ func (log *Logger) Debug(msg string, fields ...Field) {
 // Info logs a message at InfoLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (log *Logger) Info(msg string, fields ...Field) {
-	if ce := log.check(InfoLevel, msg); ce != nil {
+	if ce := log.check(InfoLevel, msg, nil); ce != nil {
 		ce.Write(fields...)
 	}
 }
@@ -196,7 +214,7 @@ func (log *Logger) Info(msg string, fields ...Field) {
 // Warn logs a message at WarnLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (log *Logger) Warn(msg string, fields ...Field) {
-	if ce := log.check(WarnLevel, msg); ce != nil {
+	if ce := log.check(WarnLevel, msg, nil); ce != nil {
 		ce.Write(fields...)
 	}
 }
@@ -204,7 +222,7 @@ func (log *Logger) Warn(msg string, fields ...Field) {
 // Error logs a message at ErrorLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (log *Logger) Error(msg string, fields ...Field) {
-	if ce := log.check(ErrorLevel, msg); ce != nil {
+	if ce := log.check(ErrorLevel, msg, nil); ce != nil {
 		ce.Write(fields...)
 	}
 }
@@ -216,7 +234,7 @@ func (log *Logger) Error(msg string, fields ...Field) {
 // "development panic"). This is useful for catching errors that are
 // recoverable, but shouldn't ever happen.
 func (log *Logger) DPanic(msg string, fields ...Field) {
-	if ce := log.check(DPanicLevel, msg); ce != nil {
+	if ce := log.check(DPanicLevel, msg, nil); ce != nil {
 		ce.Write(fields...)
 	}
 }
@@ -226,7 +244,7 @@ func (log *Logger) DPanic(msg string, fields ...Field) {
 //
 // The logger then panics, even if logging at PanicLevel is disabled.
 func (log *Logger) Panic(msg string, fields ...Field) {
-	if ce := log.check(PanicLevel, msg); ce != nil {
+	if ce := log.check(PanicLevel, msg, nil); ce != nil {
 		ce.Write(fields...)
 	}
 }
@@ -237,11 +255,67 @@ func (log *Logger) Panic(msg string, fields ...Field) {
 // The logger then calls os.Exit(1), even if logging at FatalLevel is
 // disabled.
 func (log *Logger) Fatal(msg string, fields ...Field) {
-	if ce := log.check(FatalLevel, msg); ce != nil {
+	if ce := log.check(FatalLevel, msg, nil); ce != nil {
 		ce.Write(fields...)
 	}
 }
 
+// DebugCtx logs a message at DebugLevel, like Debug, adding any fields that
+// the Logger's context extractors find in ctx.
+func (log *Logger) DebugCtx(ctx context.Context, msg string, fields ...Field) {
+	if ce := log.check(DebugLevel, msg, log.traceOf(ctx)); ce != nil {
+		ce.Write(log.contextFields(ctx, ce.Trace, fields)...)
+	}
+}
+
+// InfoCtx logs a message at InfoLevel, like Info, adding any fields that the
+// Logger's context extractors find in ctx.
+func (log *Logger) InfoCtx(ctx context.Context, msg string, fields ...Field) {
+	if ce := log.check(InfoLevel, msg, log.traceOf(ctx)); ce != nil {
+		ce.Write(log.contextFields(ctx, ce.Trace, fields)...)
+	}
+}
+
+// WarnCtx logs a message at WarnLevel, like Warn, adding any fields that the
+// Logger's context extractors find in ctx.
+func (log *Logger) WarnCtx(ctx context.Context, msg string, fields ...Field) {
+	if ce := log.check(WarnLevel, msg, log.traceOf(ctx)); ce != nil {
+		ce.Write(log.contextFields(ctx, ce.Trace, fields)...)
+	}
+}
+
+// ErrorCtx logs a message at ErrorLevel, like Error, adding any fields that
+// the Logger's context extractors find in ctx.
+func (log *Logger) ErrorCtx(ctx context.Context, msg string, fields ...Field) {
+	if ce := log.check(ErrorLevel, msg, log.traceOf(ctx)); ce != nil {
+		ce.Write(log.contextFields(ctx, ce.Trace, fields)...)
+	}
+}
+
+// DPanicCtx logs a message at DPanicLevel, like DPanic, adding any fields
+// that the Logger's context extractors find in ctx.
+func (log *Logger) DPanicCtx(ctx context.Context, msg string, fields ...Field) {
+	if ce := log.check(DPanicLevel, msg, log.traceOf(ctx)); ce != nil {
+		ce.Write(log.contextFields(ctx, ce.Trace, fields)...)
+	}
+}
+
+// PanicCtx logs a message at PanicLevel, like Panic, adding any fields that
+// the Logger's context extractors find in ctx.
+func (log *Logger) PanicCtx(ctx context.Context, msg string, fields ...Field) {
+	if ce := log.check(PanicLevel, msg, log.traceOf(ctx)); ce != nil {
+		ce.Write(log.contextFields(ctx, ce.Trace, fields)...)
+	}
+}
+
+// FatalCtx logs a message at FatalLevel, like Fatal, adding any fields that
+// the Logger's context extractors find in ctx.
+func (log *Logger) FatalCtx(ctx context.Context, msg string, fields ...Field) {
+	if ce := log.check(FatalLevel, msg, log.traceOf(ctx)); ce != nil {
+		ce.Write(log.contextFields(ctx, ce.Trace, fields)...)
+	}
+}
+
 // Sync calls the underlying Core's Sync method, flushing any buffered log
 // entries. Applications should take care to call Sync before exiting.
 func (log *Logger) Sync() error {
@@ -258,7 +332,13 @@ func (log *Logger) clone() *Logger {
 	return &copy
 }
 
-func (log *Logger) check(lvl zapcore.Level, msg string) *zapcore.CheckedEntry {
+// checkCtx is like Check, but sets the entry's trace from ctx. It's for the
+// SugaredLogger's context-aware methods.
+func (log *Logger) checkCtx(ctx context.Context, lvl zapcore.Level, msg string) *zapcore.CheckedEntry {
+	return log.check(lvl, msg, log.traceOf(ctx))
+}
+
+func (log *Logger) check(lvl zapcore.Level, msg string, trace zapcore.TraceContext) *zapcore.CheckedEntry {
 	// check must always be called directly by a method in the Logger interface
 	// (e.g., Check, Info, Fatal).
 	const callerSkipOffset = 2
@@ -276,6 +356,7 @@ func (log *Logger) check(lvl zapcore.Level, msg string) *zapcore.CheckedEntry {
 		Time:       log.clock.Now(),
 		Level:      lvl,
 		Message:    msg,
+		Trace:      trace,
 	}
 	ce := log.core.Check(ent, nil)
 	willWrite := ce != nil
@@ -283,18 +364,12 @@ func (log *Logger) check(lvl zapcore.Level, msg string) *zapcore.CheckedEntry {
 	// Set up any required terminal behavior.
 	switch ent.Level {
 	case zapcore.PanicLevel:
-		ce = ce.Should(ent, zapcore.WriteThenPanic)
+		ce = ce.After(ent, terminalHook(log.onPanic, zapcore.WriteThenPanic))
 	case zapcore.FatalLevel:
-		onFatal := log.onFatal
-		// Noop is the default value for CheckWriteAction, and it leads to
-		// continued execution after a Fatal which is unexpected.
-		if onFatal == zapcore.WriteThenNoop {
-			onFatal = zapcore.WriteThenFatal
-		}
-		ce = ce.Should(ent, onFatal)
+		ce = ce.After(ent, terminalHook(log.onFatal, zapcore.WriteThenFatal))
 	case zapcore.DPanicLevel:
 		if log.development {
-			ce = ce.Should(ent, zapcore.WriteThenPanic)
+			ce = ce.After(ent, terminalHook(log.onPanic, zapcore.WriteThenPanic))
 		}
 	}
 
@@ -323,12 +398,100 @@ func (log *Logger) check(lvl zapcore.Level, msg string) *zapcore.CheckedEntry {
 		}
 	}
 	if log.addStack.Enabled(ce.Entry.Level) {
-		ce.Entry.Stack = StackSkip("", log.callerSkip+callerSkipOffset).String
+		ce.Entry.Stack = captureStacktrace(log.callerSkip+callerSkipOffset, log.stackOpts)
+		if d := log.stackOpts.dedupe; d != nil {
+			ce.Entry.Stack = d.dedupe(ce.Entry.Stack, ce.Entry.Time)
+		}
+	}
+	if log.dumpGoroutines.Enabled(ce.Entry.Level) {
+		log.dumpAllGoroutines(ce)
 	}
 
 	return ce
 }
 
+// traceOf returns the trace span that the Logger's AddTraceContext extractor
+// finds in ctx, if any. Unlike other context extractors, it runs before
+// entries are checked, so that samplers can see the trace.
+func (log *Logger) traceOf(ctx context.Context) zapcore.TraceContext {
+	if ctx == nil || log.extractTrace == nil {
+		return nil
+	}
+	return log.extractTrace(ctx)
+}
+
+// contextFields returns the fields for the entry's trace, if any, and those
+// extracted from ctx, followed by the given fields. Callers should only
+// extract fields from entries that will be written, since extractors may be
+// expensive.
+func (log *Logger) contextFields(ctx context.Context, trace zapcore.TraceContext, fields []Field) []Field {
+	if trace == nil && (ctx == nil || len(log.ctxExtractors) == 0) {
+		return fields
+	}
+	var all []Field
+	if trace != nil {
+		all = append(all, Trace(trace))
+	}
+	for _, extract := range log.ctxExtractors {
+		all = append(all, extract(ctx)...)
+	}
+	if len(all) == 0 {
+		return fields
+	}
+	return append(all, fields...)
+}
+
+// terminalHook returns the hook to run after a Panic or Fatal entry. A nil
+// hook, or WriteThenNoop, would let execution continue after the entry, which
+// is unexpected. For example,
+//
+//	f, err := os.Open(..)
+//	if err != nil {
+//	  log.Fatal("cannot open", zap.Error(err))
+//	}
+//	fmt.Println(f.Name())
+//
+// would panic at f.Name(). So custom hooks are followed by the default action
+// in case they return.
+func terminalHook(hook zapcore.CheckWriteHook, dflt zapcore.CheckWriteAction) zapcore.CheckWriteHook {
+	switch h := hook.(type) {
+	case nil:
+		return dflt
+	case zapcore.CheckWriteAction:
+		if h == zapcore.WriteThenNoop {
+			return dflt
+		}
+		return h
+	default:
+		return terminalHooks{h, dflt}
+	}
+}
+
+// terminalHooks runs a custom hook, then the default terminal action.
+type terminalHooks struct {
+	hook zapcore.CheckWriteHook
+	dflt zapcore.CheckWriteAction
+}
+
+func (h terminalHooks) OnWrite(ce *zapcore.CheckedEntry, fields []zapcore.Field) {
+	h.hook.OnWrite(ce, fields)
+	h.dflt.OnWrite(ce, fields)
+}
+
+// dumpAllGoroutines records the stacks of all goroutines, either in place of
+// the entry's stacktrace or in the crash file.
+func (log *Logger) dumpAllGoroutines(ce *zapcore.CheckedEntry) {
+	dump := takeGoroutineDump()
+	if log.dumpPath == "" {
+		ce.Entry.Stack = string(dump)
+		return
+	}
+	if err := writeGoroutineDump(log.dumpPath, ce.Entry, dump); err != nil {
+		fmt.Fprintf(log.errorOutput, "%v Logger.check error: failed to write goroutine dump: %v\n", ce.Entry.Time.UTC(), err)
+		log.errorOutput.Sync()
+	}
+}
+
 // getCallerFrame gets caller frame. The argument skip is the number of stack
 // frames to ascend, with 0 identifying the caller of getCallerFrame. The
 // boolean ok is false if it was not possible to recover the information.
@@ -21,7 +21,9 @@
 package zap
 
 import (
+	"context"
 	"errors"
+	"runtime"
 	"sync"
 	"testing"
 
@@ -579,6 +581,162 @@ func TestLoggerCustomOnFatal(t *testing.T) {
 	}
 }
 
+type customWriteHook struct {
+	called  bool
+	fields  []Field
+	onWrite func()
+}
+
+func (h *customWriteHook) OnWrite(_ *zapcore.CheckedEntry, fields []Field) {
+	h.called = true
+	h.fields = fields
+	if h.onWrite != nil {
+		h.onWrite()
+	}
+}
+
+func TestLoggerCustomOnFatalHook(t *testing.T) {
+	t.Run("hook exits", func(t *testing.T) {
+		hook := &customWriteHook{onWrite: runtime.Goexit}
+		withLogger(t, InfoLevel, opts(OnFatal(hook)), func(logger *Logger, logs *observer.ObservedLogs) {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				logger.Fatal("fatal", Int("k", 1))
+			}()
+			<-done
+
+			assert.True(t, hook.called, "Expected custom hook to run.")
+			assert.Equal(t, []Field{Int("k", 1)}, hook.fields, "Unexpected fields passed to hook.")
+			assert.Equal(t, 1, logs.Len(), "Expected the fatal entry to be written.")
+		})
+	})
+
+	t.Run("hook returns", func(t *testing.T) {
+		hook := &customWriteHook{}
+		withLogger(t, InfoLevel, opts(OnFatal(hook)), func(logger *Logger, logs *observer.ObservedLogs) {
+			stub := exit.WithStub(func() { logger.Fatal("fatal") })
+			assert.True(t, hook.called, "Expected custom hook to run.")
+			assert.True(t, stub.Exited, "Expected to exit after a custom hook returns.")
+		})
+	})
+
+	t.Run("noop", func(t *testing.T) {
+		withLogger(t, InfoLevel, opts(OnFatal(zapcore.WriteThenNoop)), func(logger *Logger, logs *observer.ObservedLogs) {
+			stub := exit.WithStub(func() { logger.Fatal("fatal") })
+			assert.True(t, stub.Exited, "Expected WriteThenNoop to exit anyway.")
+		})
+	})
+}
+
+func TestLoggerCustomOnPanic(t *testing.T) {
+	t.Run("action", func(t *testing.T) {
+		withLogger(t, InfoLevel, opts(OnPanic(zapcore.WriteThenFatal)), func(logger *Logger, logs *observer.ObservedLogs) {
+			stub := exit.WithStub(func() { logger.Panic("panic") })
+			assert.True(t, stub.Exited, "Expected to exit instead of panicking.")
+		})
+	})
+
+	t.Run("hook returns", func(t *testing.T) {
+		hook := &customWriteHook{}
+		withLogger(t, InfoLevel, opts(OnPanic(hook)), func(logger *Logger, logs *observer.ObservedLogs) {
+			assert.Panics(t, func() { logger.Panic("panic") }, "Expected to panic after a custom hook returns.")
+			assert.True(t, hook.called, "Expected custom hook to run.")
+		})
+	})
+
+	t.Run("development dpanic", func(t *testing.T) {
+		hook := &customWriteHook{onWrite: runtime.Goexit}
+		withLogger(t, InfoLevel, opts(Development(), OnPanic(hook)), func(logger *Logger, logs *observer.ObservedLogs) {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				logger.DPanic("dpanic")
+			}()
+			<-done
+			assert.True(t, hook.called, "Expected custom hook to run on DPanic in development.")
+		})
+	})
+
+	t.Run("production dpanic", func(t *testing.T) {
+		hook := &customWriteHook{}
+		withLogger(t, InfoLevel, opts(OnPanic(hook)), func(logger *Logger, logs *observer.ObservedLogs) {
+			assert.NotPanics(t, func() { logger.DPanic("dpanic") }, "Unexpected panic on DPanic in production.")
+			assert.False(t, hook.called, "Unexpected hook call on DPanic in production.")
+		})
+	})
+}
+
+func TestLoggerContextExtractors(t *testing.T) {
+	type ctxKey struct{}
+	var calls int
+	requestID := WithContextExtractor(func(ctx context.Context) []Field {
+		calls++
+		if id, ok := ctx.Value(ctxKey{}).(string); ok {
+			return []Field{String("request", id)}
+		}
+		return nil
+	})
+	tenant := WithContextExtractor(func(context.Context) []Field {
+		return []Field{String("tenant", "acme")}
+	})
+	ctx := context.WithValue(context.Background(), ctxKey{}, "abc")
+
+	t.Run("levels", func(t *testing.T) {
+		withLogger(t, DebugLevel, opts(requestID, tenant), func(logger *Logger, logs *observer.ObservedLogs) {
+			logger.DebugCtx(ctx, "msg", Int("k", 1))
+			logger.InfoCtx(ctx, "msg", Int("k", 1))
+			logger.WarnCtx(ctx, "msg", Int("k", 1))
+			logger.ErrorCtx(ctx, "msg", Int("k", 1))
+			logger.DPanicCtx(ctx, "msg", Int("k", 1))
+			assert.Panics(t, func() { logger.PanicCtx(ctx, "msg", Int("k", 1)) }, "Expected PanicCtx to panic.")
+			stub := exit.WithStub(func() { logger.FatalCtx(ctx, "msg", Int("k", 1)) })
+			assert.True(t, stub.Exited, "Expected FatalCtx to exit.")
+
+			levels := []zapcore.Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel, DPanicLevel, PanicLevel, FatalLevel}
+			output := logs.AllUntimed()
+			require.Equal(t, len(levels), len(output), "Unexpected number of logs written out.")
+			for i, lvl := range levels {
+				assert.Equal(t, observer.LoggedEntry{
+					Entry:   zapcore.Entry{Level: lvl, Message: "msg"},
+					Context: []Field{String("request", "abc"), String("tenant", "acme"), Int("k", 1)},
+				}, output[i], "Unexpected log output.")
+			}
+		})
+	})
+
+	t.Run("disabled levels skip extraction", func(t *testing.T) {
+		calls = 0
+		withLogger(t, WarnLevel, opts(requestID), func(logger *Logger, logs *observer.ObservedLogs) {
+			logger.InfoCtx(ctx, "msg")
+			assert.Equal(t, 0, calls, "Unexpected extraction for a disabled level.")
+			logger.WarnCtx(ctx, "msg")
+			assert.Equal(t, 1, calls, "Expected extraction for an enabled level.")
+		})
+	})
+
+	t.Run("without extractors", func(t *testing.T) {
+		withLogger(t, DebugLevel, opts(AddCaller()), func(logger *Logger, logs *observer.ObservedLogs) {
+			logger.InfoCtx(ctx, "msg", Int("k", 1))
+			output := logs.AllUntimed()
+			require.Equal(t, 1, len(output), "Unexpected number of logs written out.")
+			assert.Equal(t, []Field{Int("k", 1)}, output[0].Context, "Unexpected context.")
+			assert.Regexp(t, `.+/logger_test.go:[\d]+$`, output[0].Caller.String(), "Unexpected caller.")
+		})
+	})
+
+	t.Run("options don't leak into parents", func(t *testing.T) {
+		withLogger(t, DebugLevel, opts(requestID), func(logger *Logger, logs *observer.ObservedLogs) {
+			logger.WithOptions(tenant).InfoCtx(ctx, "child")
+			logger.InfoCtx(ctx, "parent")
+			output := logs.AllUntimed()
+			require.Equal(t, 2, len(output), "Unexpected number of logs written out.")
+			assert.Equal(t, []Field{String("request", "abc"), String("tenant", "acme")}, output[0].Context, "Unexpected child context.")
+			assert.Equal(t, []Field{String("request", "abc")}, output[1].Context, "Unexpected parent context.")
+		})
+	})
+}
+
 func TestNopLogger(t *testing.T) {
 	logger := NewNop()
 
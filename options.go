@@ -21,7 +21,9 @@
 package zap
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"go.uber.org/zap/zapcore"
 )
@@ -119,6 +121,85 @@ func AddStacktrace(lvl zapcore.LevelEnabler) Option {
 	})
 }
 
+// A StacktraceOption limits the stacktraces recorded by a Logger configured
+// with AddStacktraceWithOptions.
+type StacktraceOption interface {
+	apply(*stacktraceOptions)
+}
+
+type stacktraceOptionFunc func(*stacktraceOptions)
+
+func (f stacktraceOptionFunc) apply(opts *stacktraceOptions) {
+	f(opts)
+}
+
+// StacktraceFrames filters and limits the frames of each stack trace as it's
+// captured, starting from the logging call site. It takes the same
+// configuration, and drops the same frames, as
+// zapcore.StacktraceEncoderOfFrames does when encoding; filtering at capture
+// time also skips the cost of formatting frames that would be dropped.
+func StacktraceFrames(cfg zapcore.StacktraceFramesConfig) StacktraceOption {
+	cfg.SkipPrefixes = append([]string(nil), cfg.SkipPrefixes...)
+	return stacktraceOptionFunc(func(opts *stacktraceOptions) {
+		opts.frames = cfg
+	})
+}
+
+// StacktraceDedupe records each distinct stack trace in full at most once
+// per window. Full stack traces are headed by a line naming their 64-bit
+// FNV-1a hash, in hex, like "stacktrace 8f3c...". Within the window, repeats
+// are replaced with a short note naming the same hash, like
+// "stacktrace 8f3c... repeated".
+//
+// Loggers derived from this one share the record of recent stack traces,
+// which holds at most 1024 of them; beyond that, the one logged in full
+// longest ago is forgotten.
+func StacktraceDedupe(window time.Duration) StacktraceOption {
+	return stacktraceOptionFunc(func(opts *stacktraceOptions) {
+		opts.dedupe = newStacktraceDeduper(window)
+	})
+}
+
+// AddStacktraceWithOptions configures the Logger to record a stack trace for
+// all messages at or above a given level, like AddStacktrace, limited by the
+// given options. It replaces any options set previously.
+func AddStacktraceWithOptions(lvl zapcore.LevelEnabler, opts ...StacktraceOption) Option {
+	var stackOpts stacktraceOptions
+	for _, opt := range opts {
+		opt.apply(&stackOpts)
+	}
+	return optionFunc(func(log *Logger) {
+		log.addStack = lvl
+		log.stackOpts = stackOpts
+	})
+}
+
+// DumpGoroutinesOn configures the Logger to record the stacks of all
+// goroutines, as runtime.Stack does, for messages at or above the given
+// level. The dump replaces the entry's stack trace, since it includes the
+// logging goroutine's stack.
+//
+// A dump helps explain deadlocks and resource exhaustion when a process dies
+// from Fatal or Panic, but taking it stops the world and its output can be
+// large, so reserve it for those levels.
+func DumpGoroutinesOn(lvl zapcore.LevelEnabler) Option {
+	return optionFunc(func(log *Logger) {
+		log.dumpGoroutines = lvl
+		log.dumpPath = ""
+	})
+}
+
+// DumpGoroutinesToFile is like DumpGoroutinesOn, but appends each dump to the
+// file at path, headed by the time, level, and message of the entry, instead
+// of attaching it to the entry. The file is synced before the entry is
+// written, so the dump survives Fatal exiting the process.
+func DumpGoroutinesToFile(lvl zapcore.LevelEnabler, path string) Option {
+	return optionFunc(func(log *Logger) {
+		log.dumpGoroutines = lvl
+		log.dumpPath = path
+	})
+}
+
 // IncreaseLevel increase the level of the logger. It has no effect if
 // the passed in level tries to decrease the level of the logger.
 func IncreaseLevel(lvl zapcore.LevelEnabler) Option {
@@ -132,10 +213,25 @@ func IncreaseLevel(lvl zapcore.LevelEnabler) Option {
 	})
 }
 
-// OnFatal sets the action to take on fatal logs.
-func OnFatal(action zapcore.CheckWriteAction) Option {
+// OnFatal sets the hook to run after fatal logs are written. Besides the
+// zapcore.CheckWriteAction constants, it accepts any zapcore.CheckWriteHook,
+// for example to run shutdown logic, report to a crash service, or exit with
+// a particular code. If a custom hook returns, the Logger exits anyway, as
+// with zapcore.WriteThenFatal; zapcore.WriteThenNoop is treated the same way.
+func OnFatal(hook zapcore.CheckWriteHook) Option {
 	return optionFunc(func(log *Logger) {
-		log.onFatal = action
+		log.onFatal = hook
+	})
+}
+
+// OnPanic sets the hook to run after panic logs are written, and after dpanic
+// logs in development. If a custom hook returns, the Logger panics anyway, as
+// with zapcore.WriteThenPanic; zapcore.WriteThenNoop is treated the same
+// way. Use zapcore.WriteThenGoexit or zapcore.WriteThenFatal to end the
+// goroutine or process instead of panicking.
+func OnPanic(hook zapcore.CheckWriteHook) Option {
+	return optionFunc(func(log *Logger) {
+		log.onPanic = hook
 	})
 }
 
@@ -146,3 +242,41 @@ func WithClock(clock zapcore.Clock) Option {
 		log.clock = clock
 	})
 }
+
+// RecoverLevel sets the level at which RecoverAndLog and Go log recovered
+// panics. The default is ErrorLevel. At PanicLevel or FatalLevel, the usual
+// terminal behavior follows the entry.
+func RecoverLevel(lvl zapcore.Level) Option {
+	return optionFunc(func(log *Logger) {
+		log.recoverLevel = lvl
+	})
+}
+
+// RecoverRepanic configures RecoverAndLog and Go to flush the Logger and
+// panic again with the recovered value after logging it, so that the panic
+// still crashes the process.
+func RecoverRepanic(repanic bool) Option {
+	return optionFunc(func(log *Logger) {
+		log.recoverRepanic = repanic
+	})
+}
+
+// WithContextExtractor adds a function that finds fields, such as request or
+// trace IDs, in the context.Context passed to the Logger's context-aware
+// methods, like InfoCtx. The extracted fields come before the fields passed
+// at the log site. Extractors only run for entries that will be written, and
+// they run in the order they were added.
+func WithContextExtractor(extract func(context.Context) []Field) Option {
+	return optionFunc(func(log *Logger) {
+		n := len(log.ctxExtractors)
+		log.ctxExtractors = append(log.ctxExtractors[:n:n], extract)
+	})
+}
+
+// NamedLevels filters the Logger's entries by the levels that reg sets for
+// their logger names, as built by Named. The Logger's Core must enable every
+// level that reg enables, for example by using reg as its LevelEnabler. See
+// LevelRegistry for details.
+func NamedLevels(reg *LevelRegistry) Option {
+	return WrapCore(reg.Core)
+}
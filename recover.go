@@ -0,0 +1,75 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zap
+
+// RecoverAndLog recovers from a panic and logs the panic value under the
+// "panic" key, along with the stack trace of the goroutine that panicked. The
+// entry is logged at ErrorLevel unless the RecoverLevel option says
+// otherwise, and it includes any fields passed at the call site as well as
+// any fields accumulated on the logger. If the logger adds callers, the
+// caller is the function that panicked.
+//
+// RecoverAndLog has no effect unless it's deferred directly, since that's the
+// only way the recover built-in can stop a panic:
+//
+//	defer logger.RecoverAndLog("worker crashed", zap.Int("worker", id))
+//
+// If the RecoverRepanic option is set, RecoverAndLog then flushes the
+// logger and panics again with the same value.
+func (log *Logger) RecoverAndLog(msg string, fields ...Field) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	// Point the caller and stack trace at the function that panicked rather
+	// than at the runtime's panic machinery.
+	skip, ok := panicSkip()
+	if !ok {
+		skip = 1
+	}
+	l := log.clone()
+	l.callerSkip = skip - 1
+	if ce := l.check(log.recoverLevel, msg, nil); ce != nil {
+		if ce.Entry.Stack == "" {
+			ce.Entry.Stack = captureStacktrace(skip, log.stackOpts)
+			if d := log.stackOpts.dedupe; d != nil {
+				ce.Entry.Stack = d.dedupe(ce.Entry.Stack, ce.Entry.Time)
+			}
+		}
+		ce.Write(append(fields[:len(fields):len(fields)], Any("panic", r))...)
+	}
+
+	if log.recoverRepanic {
+		log.Sync()
+		panic(r)
+	}
+}
+
+// Go runs fn in a new goroutine. If fn panics, the panic is logged with
+// RecoverAndLog, so the Logger's RecoverLevel and RecoverRepanic options
+// apply.
+func Go(log *Logger, fn func()) {
+	go func() {
+		defer log.RecoverAndLog("panic in goroutine")
+		fn()
+	}()
+}
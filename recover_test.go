@@ -0,0 +1,157 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zap
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func panicWith(v interface{}) {
+	panic(v)
+}
+
+func panicOutOfRange() {
+	var s []int
+	_ = s[len(s)]
+}
+
+func TestRecoverAndLog(t *testing.T) {
+	tests := []struct {
+		desc     string
+		f        func()
+		value    interface{}
+		function string
+	}{
+		{
+			desc:     "panic value",
+			f:        func() { panicWith("boom") },
+			value:    "boom",
+			function: "go.uber.org/zap.panicWith",
+		},
+		{
+			desc:     "runtime error",
+			f:        panicOutOfRange,
+			function: "go.uber.org/zap.panicOutOfRange",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			withLogger(t, DebugLevel, opts(AddCaller(), Fields(String("service", "test"))), func(logger *Logger, logs *observer.ObservedLogs) {
+				func() {
+					defer logger.RecoverAndLog("recovered", Int("id", 1))
+					tt.f()
+				}()
+
+				require.Equal(t, 1, logs.Len(), "Expected the panic to be logged.")
+				entry := logs.AllUntimed()[0]
+				assert.Equal(t, ErrorLevel, entry.Level, "Unexpected level.")
+				assert.Equal(t, "recovered", entry.Message, "Unexpected message.")
+				assert.Equal(t, tt.function, entry.Caller.Function, "Expected caller to be the panicking function.")
+				assert.True(t, strings.HasPrefix(entry.Stack, tt.function+"\n"), "Expected stacktrace to start at the panicking function, got:\n%v", entry.Stack)
+
+				ctx := entry.ContextMap()
+				assert.Equal(t, "test", ctx["service"], "Expected logger context.")
+				assert.Equal(t, int64(1), ctx["id"], "Expected call site fields.")
+				if tt.value != nil {
+					assert.Equal(t, tt.value, ctx["panic"], "Unexpected panic value.")
+				} else {
+					assert.Contains(t, ctx["panic"], "index out of range", "Unexpected panic value.")
+				}
+			})
+		})
+	}
+}
+
+func TestRecoverAndLogNoPanic(t *testing.T) {
+	withLogger(t, DebugLevel, nil, func(logger *Logger, logs *observer.ObservedLogs) {
+		func() {
+			defer logger.RecoverAndLog("recovered")
+		}()
+		assert.Equal(t, 0, logs.Len(), "Unexpected log without a panic.")
+	})
+}
+
+func TestRecoverAndLogOptions(t *testing.T) {
+	err := errors.New("boom")
+
+	t.Run("level", func(t *testing.T) {
+		withLogger(t, DebugLevel, opts(RecoverLevel(WarnLevel)), func(logger *Logger, logs *observer.ObservedLogs) {
+			func() {
+				defer logger.RecoverAndLog("recovered")
+				panicWith(err)
+			}()
+			require.Equal(t, 1, logs.Len(), "Expected the panic to be logged.")
+			entry := logs.AllUntimed()[0]
+			assert.Equal(t, WarnLevel, entry.Level, "Unexpected level.")
+			assert.Equal(t, "boom", entry.ContextMap()["panic"], "Unexpected panic value.")
+		})
+	})
+
+	t.Run("disabled level", func(t *testing.T) {
+		withLogger(t, ErrorLevel, opts(RecoverLevel(WarnLevel)), func(logger *Logger, logs *observer.ObservedLogs) {
+			assert.NotPanics(t, func() {
+				defer logger.RecoverAndLog("recovered")
+				panicWith(err)
+			}, "Expected panic to be recovered even if not logged.")
+			assert.Equal(t, 0, logs.Len(), "Unexpected log at a disabled level.")
+		})
+	})
+
+	t.Run("repanic", func(t *testing.T) {
+		var out bytes.Buffer
+		sink := &zapcore.BufferedWriteSyncer{WS: zapcore.AddSync(&out), FlushInterval: time.Hour}
+		defer sink.Stop()
+		core := zapcore.NewCore(zapcore.NewJSONEncoder(NewProductionEncoderConfig()), sink, DebugLevel)
+		logger := New(core, RecoverRepanic(true))
+
+		assert.PanicsWithValue(t, err, func() {
+			defer logger.RecoverAndLog("recovered")
+			panicWith(err)
+		}, "Expected to panic again with the recovered value.")
+		assert.Contains(t, out.String(), `"msg":"recovered"`, "Expected the logger to be flushed before panicking again.")
+	})
+}
+
+func TestGo(t *testing.T) {
+	done := make(chan struct{})
+	logged := Hooks(func(zapcore.Entry) error {
+		close(done)
+		return nil
+	})
+	withLogger(t, DebugLevel, opts(logged), func(logger *Logger, logs *observer.ObservedLogs) {
+		Go(logger, func() { panicWith("boom") })
+		<-done
+
+		require.Equal(t, 1, logs.Len(), "Expected the panic to be logged.")
+		assert.Equal(t, "boom", logs.AllUntimed()[0].ContextMap()["panic"], "Unexpected panic value.")
+	})
+}
@@ -96,9 +96,19 @@ func RegisterSink(scheme string, factory func(*url.URL) (Sink, error)) error {
 }
 
 func newSink(rawURL string) (Sink, error) {
+	u, factory, err := lookupSink(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return factory(u)
+}
+
+// lookupSink parses a sink URL and finds the factory for its scheme, without
+// opening the sink.
+func lookupSink(rawURL string) (*url.URL, func(*url.URL) (Sink, error), error) {
 	u, err := url.Parse(rawURL)
 	if err != nil {
-		return nil, fmt.Errorf("can't parse %q as a URL: %v", rawURL, err)
+		return nil, nil, fmt.Errorf("can't parse %q as a URL: %v", rawURL, err)
 	}
 	if u.Scheme == "" {
 		u.Scheme = schemeFile
@@ -108,35 +118,43 @@ func newSink(rawURL string) (Sink, error) {
 	factory, ok := _sinkFactories[u.Scheme]
 	_sinkMutex.RUnlock()
 	if !ok {
-		return nil, &errSinkNotFound{u.Scheme}
+		return nil, nil, &errSinkNotFound{u.Scheme}
 	}
-	return factory(u)
+	return u, factory, nil
 }
 
 func newFileSink(u *url.URL) (Sink, error) {
+	if err := checkFileURL(u); err != nil {
+		return nil, err
+	}
+	switch u.Path {
+	case "stdout":
+		return nopCloserSink{os.Stdout}, nil
+	case "stderr":
+		return nopCloserSink{os.Stderr}, nil
+	}
+	return os.OpenFile(u.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+}
+
+// checkFileURL reports whether a URL with the "file" scheme is valid.
+func checkFileURL(u *url.URL) error {
 	if u.User != nil {
-		return nil, fmt.Errorf("user and password not allowed with file URLs: got %v", u)
+		return fmt.Errorf("user and password not allowed with file URLs: got %v", u)
 	}
 	if u.Fragment != "" {
-		return nil, fmt.Errorf("fragments not allowed with file URLs: got %v", u)
+		return fmt.Errorf("fragments not allowed with file URLs: got %v", u)
 	}
 	if u.RawQuery != "" {
-		return nil, fmt.Errorf("query parameters not allowed with file URLs: got %v", u)
+		return fmt.Errorf("query parameters not allowed with file URLs: got %v", u)
 	}
 	// Error messages are better if we check hostname and port separately.
 	if u.Port() != "" {
-		return nil, fmt.Errorf("ports not allowed with file URLs: got %v", u)
+		return fmt.Errorf("ports not allowed with file URLs: got %v", u)
 	}
 	if hn := u.Hostname(); hn != "" && hn != "localhost" {
-		return nil, fmt.Errorf("file URLs must leave host empty or use localhost: got %v", u)
-	}
-	switch u.Path {
-	case "stdout":
-		return nopCloserSink{os.Stdout}, nil
-	case "stderr":
-		return nopCloserSink{os.Stderr}, nil
+		return fmt.Errorf("file URLs must leave host empty or use localhost: got %v", u)
 	}
-	return os.OpenFile(u.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	return nil
 }
 
 func normalizeScheme(s string) (string, error) {
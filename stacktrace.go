@@ -21,10 +21,18 @@
 package zap
 
 import (
+	"container/list"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
 	"runtime"
+	"strings"
 	"sync"
+	"time"
 
 	"go.uber.org/zap/internal/bufferpool"
+	"go.uber.org/zap/zapcore"
 )
 
 var (
@@ -36,31 +44,54 @@ var (
 )
 
 func takeStacktrace(skip int) string {
+	return captureStacktrace(skip+1, stacktraceOptions{})
+}
+
+// captureStacktrace takes a stacktrace starting skip frames above its caller,
+// limited by opts.
+func captureStacktrace(skip int, opts stacktraceOptions) string {
 	buffer := bufferpool.Get()
 	defer buffer.Free()
 	programCounters := _stacktracePool.Get().(*programCounters)
 	defer _stacktracePool.Put(programCounters)
 
+	// Without filtering, there's no need to look past the frame limit. Take
+	// one extra frame so that we can still tell whether the last frame is
+	// the bottom of the stack.
+	pcs := programCounters.pcs
+	maxFrames := opts.frames.MaxDepth
+	capped := maxFrames > 0 && !opts.frames.Filters()
+	if capped && maxFrames+1 < len(pcs) {
+		pcs = pcs[:maxFrames+1]
+	}
+
 	var numFrames int
 	for {
-		// Skip the call to runtime.Callers and takeStacktrace so that the
-		// program counters start at the caller of takeStacktrace.
-		numFrames = runtime.Callers(skip+2, programCounters.pcs)
-		if numFrames < len(programCounters.pcs) {
+		// Skip the call to runtime.Callers and captureStacktrace so that the
+		// program counters start at the caller of captureStacktrace.
+		numFrames = runtime.Callers(skip+2, pcs)
+		if numFrames < len(pcs) || capped && numFrames > maxFrames {
 			break
 		}
 		// Don't put the too-short counter slice back into the pool; this lets
 		// the pool adjust if we consistently take deep stacktraces.
 		programCounters = newProgramCounters(len(programCounters.pcs) * 2)
+		pcs = programCounters.pcs
 	}
 
 	i := 0
-	frames := runtime.CallersFrames(programCounters.pcs[:numFrames])
+	frames := runtime.CallersFrames(pcs[:numFrames])
 
 	// Note: On the last iteration, frames.Next() returns false, with a valid
 	// frame, but we ignore this frame. The last frame is a a runtime frame which
 	// adds noise, since it's only either runtime.main or runtime.goexit.
 	for frame, more := frames.Next(); more; frame, more = frames.Next() {
+		if opts.frames.Skips(frame.Function) {
+			continue
+		}
+		if maxFrames > 0 && i == maxFrames {
+			break
+		}
 		if i != 0 {
 			buffer.AppendByte('\n')
 		}
@@ -76,6 +107,148 @@ func takeStacktrace(skip int) string {
 	return buffer.String()
 }
 
+// panicSkip returns how many frames above its caller the function that
+// panicked is, when the caller was deferred and is running because of a
+// panic. Frames from the runtime's panic machinery, such as those for nil
+// dereferences or out-of-range indexes, aren't counted as the panicking
+// function.
+func panicSkip() (int, bool) {
+	programCounters := _stacktracePool.Get().(*programCounters)
+	defer _stacktracePool.Put(programCounters)
+
+	// Skip the call to runtime.Callers and panicSkip.
+	numFrames := runtime.Callers(2, programCounters.pcs)
+	frames := runtime.CallersFrames(programCounters.pcs[:numFrames])
+
+	panicking := false
+	for i := 0; ; i++ {
+		frame, more := frames.Next()
+		if panicking && !strings.HasPrefix(frame.Function, "runtime.") {
+			return i, true
+		}
+		if frame.Function == "runtime.gopanic" {
+			panicking = true
+		}
+		if !more {
+			return 0, false
+		}
+	}
+}
+
+// stacktraceOptions limits the stacktraces a Logger captures. The zero value
+// captures complete stacktraces.
+type stacktraceOptions struct {
+	frames zapcore.StacktraceFramesConfig
+	dedupe *stacktraceDeduper
+}
+
+// _maxDedupedStacktraces bounds the number of stacktraces remembered at
+// once. Beyond it, the stacktrace logged in full longest ago is forgotten,
+// even if it's still within the window.
+const _maxDedupedStacktraces = 1024
+
+// stacktraceDeduper replaces stacktraces seen recently with their hashes.
+type stacktraceDeduper struct {
+	window time.Duration
+
+	mu    sync.Mutex
+	seen  map[uint64]*list.Element
+	order *list.List // of *dedupedStacktrace, least recently logged in full first
+}
+
+type dedupedStacktrace struct {
+	hash   uint64
+	logged time.Time // when the full stacktrace was last logged
+}
+
+func newStacktraceDeduper(window time.Duration) *stacktraceDeduper {
+	return &stacktraceDeduper{
+		window: window,
+		seen:   make(map[uint64]*list.Element),
+		order:  list.New(),
+	}
+}
+
+// dedupe returns the stacktrace headed by its hash if it wasn't logged in
+// full within the window before now, and a note with its hash otherwise.
+func (d *stacktraceDeduper) dedupe(stack string, now time.Time) string {
+	hash := stacktraceHash(stack)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if e, ok := d.seen[hash]; ok {
+		st := e.Value.(*dedupedStacktrace)
+		if now.Sub(st.logged) < d.window {
+			return fmt.Sprintf("stacktrace %016x repeated", hash)
+		}
+		st.logged = now
+		d.order.MoveToBack(e)
+	} else {
+		d.forget(now)
+		d.seen[hash] = d.order.PushBack(&dedupedStacktrace{hash: hash, logged: now})
+	}
+	return fmt.Sprintf("stacktrace %016x\n%s", hash, stack)
+}
+
+// forget drops expired stacktraces, and then the oldest ones until there's
+// room for another.
+func (d *stacktraceDeduper) forget(now time.Time) {
+	for e := d.order.Front(); e != nil; e = d.order.Front() {
+		st := e.Value.(*dedupedStacktrace)
+		if now.Sub(st.logged) < d.window && d.order.Len() < _maxDedupedStacktraces {
+			return
+		}
+		d.order.Remove(e)
+		delete(d.seen, st.hash)
+	}
+}
+
+// stacktraceHash returns the 64-bit FNV-1a hash of a stacktrace.
+func stacktraceHash(stack string) uint64 {
+	h := fnv.New64a()
+	io.WriteString(h, stack)
+	return h.Sum64()
+}
+
+// takeGoroutineDump returns the stacks of all goroutines, formatted as by
+// runtime.Stack.
+func takeGoroutineDump() []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true /* all */)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
+
+// writeGoroutineDump appends a goroutine dump to the file at path, headed by
+// the entry that triggered it, and syncs the file so that the dump survives
+// the process exiting.
+func writeGoroutineDump(path string, ent zapcore.Entry, dump []byte) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if _, err := fmt.Fprintf(f, "=== %v %v %q\n", ent.Time.UTC().Format(time.RFC3339Nano), ent.Level.CapitalString(), ent.Message); err != nil {
+		return err
+	}
+	if _, err := f.Write(dump); err != nil {
+		return err
+	}
+	if _, err := f.Write([]byte("\n")); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
 type programCounters struct {
 	pcs []uintptr
 }
@@ -21,8 +21,17 @@
 package zap
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"go.uber.org/zap/internal/ztest"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -67,6 +76,199 @@ func TestTakeStacktraceWithSkipInnerFunc(t *testing.T) {
 	)
 }
 
+func TestCaptureStacktraceOptions(t *testing.T) {
+	tests := []struct {
+		desc       string
+		opts       stacktraceOptions
+		wantFrames int
+		wantFirst  string
+	}{
+		{
+			desc:       "max frames",
+			opts:       stacktraceOptions{frames: zapcore.StacktraceFramesConfig{MaxDepth: 1}},
+			wantFrames: 1,
+			wantFirst:  "go.uber.org/zap.TestCaptureStacktraceOptions",
+		},
+		{
+			desc:      "skip prefixes",
+			opts:      stacktraceOptions{frames: zapcore.StacktraceFramesConfig{SkipPrefixes: []string{"go.uber.org/zap."}}},
+			wantFirst: "testing.",
+		},
+		{
+			desc:      "skip zap",
+			opts:      stacktraceOptions{frames: zapcore.StacktraceFramesConfig{SkipZap: true}},
+			wantFirst: "testing.",
+		},
+		{
+			desc: "skip prefixes and max frames",
+			opts: stacktraceOptions{frames: zapcore.StacktraceFramesConfig{
+				MaxDepth:     1,
+				SkipPrefixes: []string{"go.uber.org/zap."},
+			}},
+			wantFrames: 1,
+			wantFirst:  "testing.",
+		},
+	}
+
+	full := strings.Split(takeStacktrace(0), "\n")
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			lines := strings.Split(captureStacktrace(0, tt.opts), "\n")
+			require.NotEmpty(t, lines, "Expected stacktrace to have at least one frame.")
+			assert.Contains(t, lines[0], tt.wantFirst, "Unexpected first frame.")
+			if tt.wantFrames > 0 {
+				assert.Len(t, lines, 2*tt.wantFrames, "Unexpected number of frames.")
+			} else {
+				assert.True(t, len(lines) < len(full), "Expected some frames to be skipped.")
+			}
+		})
+	}
+}
+
+func TestStacktraceDeduper(t *testing.T) {
+	d := newStacktraceDeduper(time.Minute)
+	now := time.Unix(0, 0)
+	full := func(stack string) string {
+		return fmt.Sprintf("stacktrace %016x\n%s", stacktraceHash(stack), stack)
+	}
+	note := fmt.Sprintf("stacktrace %016x repeated", stacktraceHash("a"))
+
+	assert.Equal(t, full("a"), d.dedupe("a", now), "Expected first stacktrace in full.")
+	assert.Equal(t, note, d.dedupe("a", now.Add(time.Second)), "Expected repeat within window to be replaced.")
+	assert.Equal(t, full("b"), d.dedupe("b", now.Add(time.Second)), "Expected distinct stacktrace in full.")
+	assert.Equal(t, full("a"), d.dedupe("a", now.Add(time.Minute)), "Expected repeat after window in full.")
+
+	for i := 0; i < _maxDedupedStacktraces; i++ {
+		d.dedupe(fmt.Sprint(i), now)
+	}
+	d.dedupe("c", now.Add(2*time.Minute))
+	assert.Len(t, d.seen, 1, "Expected expired stacktraces to be forgotten.")
+}
+
+func TestStacktraceDeduperBound(t *testing.T) {
+	d := newStacktraceDeduper(time.Hour)
+	now := time.Unix(0, 0)
+
+	// None of these expire, so the oldest are forgotten to stay in bounds.
+	for i := 0; i < 2*_maxDedupedStacktraces; i++ {
+		d.dedupe(fmt.Sprint(i), now.Add(time.Duration(i)*time.Millisecond))
+	}
+	assert.Len(t, d.seen, _maxDedupedStacktraces, "Expected stacktraces to be bounded.")
+	assert.Equal(t, _maxDedupedStacktraces, d.order.Len(), "Expected order to match remembered stacktraces.")
+
+	later := now.Add(time.Minute)
+	assert.True(t, strings.HasPrefix(d.dedupe("0", later), "stacktrace "), "Expected forgotten stacktrace in full.")
+	last := fmt.Sprint(2*_maxDedupedStacktraces - 1)
+	assert.Equal(
+		t,
+		fmt.Sprintf("stacktrace %016x repeated", stacktraceHash(last)),
+		d.dedupe(last, later),
+		"Expected recent stacktrace to still be remembered.",
+	)
+}
+
+func TestLoggerAddStacktraceWithOptions(t *testing.T) {
+	clock := constantClock(time.Unix(0, 0))
+	opts := []Option{
+		WithClock(clock),
+		AddStacktraceWithOptions(
+			WarnLevel,
+			StacktraceFrames(zapcore.StacktraceFramesConfig{MaxDepth: 2}),
+			StacktraceDedupe(time.Minute),
+		),
+	}
+	withLogger(t, DebugLevel, opts, func(logger *Logger, logs *observer.ObservedLogs) {
+		for i := 0; i < 2; i++ {
+			logger.Warn("same place")
+		}
+		logger.Info("no stack")
+
+		entries := logs.AllUntimed()
+		require.Len(t, entries, 3, "Unexpected number of entries.")
+		first := strings.Split(entries[0].Stack, "\n")
+		require.Len(t, first, 5, "Expected a header and two frames.")
+		assert.Contains(t, first[1], "go.uber.org/zap.TestLoggerAddStacktraceWithOptions", "Expected stack to start at the call site.")
+
+		// The repeat names the hash that headed the full stack trace.
+		var hash uint64
+		_, err := fmt.Sscanf(first[0], "stacktrace %016x", &hash)
+		require.NoError(t, err, "Expected full stack to be headed by its hash.")
+		assert.Equal(t, stacktraceHash(strings.Join(first[1:], "\n")), hash, "Unexpected hash of full stack.")
+		assert.Equal(t, fmt.Sprintf("stacktrace %016x repeated", hash), entries[1].Stack, "Expected repeated stack to be replaced with its hash.")
+		assert.Empty(t, entries[2].Stack, "Expected no stack below the stacktrace level.")
+	})
+}
+
+func blockForGoroutineDump(started chan<- struct{}, stop <-chan struct{}) {
+	close(started)
+	<-stop
+}
+
+func withBlockedGoroutine(t *testing.T, f func()) {
+	started, stop := make(chan struct{}), make(chan struct{})
+	go blockForGoroutineDump(started, stop)
+	defer close(stop)
+	<-started
+	f()
+}
+
+func TestDumpGoroutinesOn(t *testing.T) {
+	opts := []Option{AddStacktrace(ErrorLevel), DumpGoroutinesOn(ErrorLevel)}
+	withLogger(t, DebugLevel, opts, func(logger *Logger, logs *observer.ObservedLogs) {
+		withBlockedGoroutine(t, func() {
+			logger.Warn("no dump")
+			logger.Error("dump")
+		})
+
+		entries := logs.AllUntimed()
+		require.Len(t, entries, 2, "Unexpected number of entries.")
+		assert.Empty(t, entries[0].Stack, "Expected no dump below the level.")
+		assert.True(t, strings.HasPrefix(entries[1].Stack, "goroutine "), "Expected a goroutine dump in place of the stack trace.")
+		assert.Contains(t, entries[1].Stack, "TestDumpGoroutinesOn", "Expected the dump to include the logging goroutine.")
+		assert.Contains(t, entries[1].Stack, "blockForGoroutineDump", "Expected the dump to include other goroutines.")
+	})
+}
+
+func TestDumpGoroutinesToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zap-dump")
+	require.NoError(t, err, "Failed to create temporary directory.")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "crash.log")
+
+	errBuf := &ztest.Buffer{}
+	opts := []Option{
+		AddStacktrace(ErrorLevel),
+		DumpGoroutinesToFile(ErrorLevel, path),
+		ErrorOutput(errBuf),
+		WithClock(constantClock(time.Date(2077, 1, 23, 10, 15, 13, 0, time.UTC))),
+	}
+	withLogger(t, DebugLevel, opts, func(logger *Logger, logs *observer.ObservedLogs) {
+		withBlockedGoroutine(t, func() {
+			logger.Error("first")
+			logger.Error("second")
+		})
+
+		entries := logs.AllUntimed()
+		require.Len(t, entries, 2, "Unexpected number of entries.")
+		assert.Contains(t, entries[0].Stack, "TestDumpGoroutinesToFile", "Expected the usual stack trace on the entry.")
+		assert.NotContains(t, entries[0].Stack, "goroutine ", "Expected no dump on the entry.")
+
+		contents, err := ioutil.ReadFile(path)
+		require.NoError(t, err, "Failed to read crash file.")
+		assert.True(t, strings.HasPrefix(string(contents), `=== 2077-01-23T10:15:13Z ERROR "first"`+"\ngoroutine "), "Unexpected crash file header.")
+		assert.Contains(t, string(contents), `=== 2077-01-23T10:15:13Z ERROR "second"`, "Expected dumps to be appended.")
+		assert.Contains(t, string(contents), "blockForGoroutineDump", "Expected the dump to include other goroutines.")
+		assert.Empty(t, errBuf.String(), "Unexpected internal errors.")
+	})
+
+	opts = []Option{DumpGoroutinesToFile(ErrorLevel, filepath.Join(dir, "missing", "crash.log")), ErrorOutput(errBuf)}
+	withLogger(t, DebugLevel, opts, func(logger *Logger, logs *observer.ObservedLogs) {
+		logger.Error("fails")
+		assert.Equal(t, 1, logs.Len(), "Expected the entry to be logged anyway.")
+		assert.Contains(t, errBuf.String(), "failed to write goroutine dump", "Expected an internal error.")
+	})
+}
+
 func BenchmarkTakeStacktrace(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		takeStacktrace(0)
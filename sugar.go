@@ -21,6 +21,7 @@
 package zap
 
 import (
+	"context"
 	"fmt"
 
 	"go.uber.org/zap/zapcore"
@@ -210,6 +211,48 @@ func (s *SugaredLogger) Fatalw(msg string, keysAndValues ...interface{}) {
 	s.log(FatalLevel, msg, nil, keysAndValues)
 }
 
+// DebugCtx logs a message with some additional context, like Debugw, adding
+// any fields that the Logger's context extractors find in ctx.
+func (s *SugaredLogger) DebugCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	s.logCtx(ctx, DebugLevel, msg, keysAndValues)
+}
+
+// InfoCtx logs a message with some additional context, like Infow, adding
+// any fields that the Logger's context extractors find in ctx.
+func (s *SugaredLogger) InfoCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	s.logCtx(ctx, InfoLevel, msg, keysAndValues)
+}
+
+// WarnCtx logs a message with some additional context, like Warnw, adding
+// any fields that the Logger's context extractors find in ctx.
+func (s *SugaredLogger) WarnCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	s.logCtx(ctx, WarnLevel, msg, keysAndValues)
+}
+
+// ErrorCtx logs a message with some additional context, like Errorw, adding
+// any fields that the Logger's context extractors find in ctx.
+func (s *SugaredLogger) ErrorCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	s.logCtx(ctx, ErrorLevel, msg, keysAndValues)
+}
+
+// DPanicCtx logs a message with some additional context, like DPanicw,
+// adding any fields that the Logger's context extractors find in ctx.
+func (s *SugaredLogger) DPanicCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	s.logCtx(ctx, DPanicLevel, msg, keysAndValues)
+}
+
+// PanicCtx logs a message with some additional context, like Panicw, adding
+// any fields that the Logger's context extractors find in ctx.
+func (s *SugaredLogger) PanicCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	s.logCtx(ctx, PanicLevel, msg, keysAndValues)
+}
+
+// FatalCtx logs a message with some additional context, like Fatalw, adding
+// any fields that the Logger's context extractors find in ctx.
+func (s *SugaredLogger) FatalCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	s.logCtx(ctx, FatalLevel, msg, keysAndValues)
+}
+
 // Sync flushes any buffered log entries.
 func (s *SugaredLogger) Sync() error {
 	return s.base.Sync()
@@ -228,6 +271,12 @@ func (s *SugaredLogger) log(lvl zapcore.Level, template string, fmtArgs []interf
 	}
 }
 
+func (s *SugaredLogger) logCtx(ctx context.Context, lvl zapcore.Level, msg string, keysAndValues []interface{}) {
+	if ce := s.base.checkCtx(ctx, lvl, msg); ce != nil {
+		ce.Write(s.base.contextFields(ctx, ce.Trace, s.sweetenFields(keysAndValues))...)
+	}
+}
+
 // getMessage format with Sprint, Sprintf, or neither.
 func getMessage(template string, fmtArgs []interface{}) string {
 	if len(fmtArgs) == 0 {
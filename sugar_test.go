@@ -21,6 +21,7 @@
 package zap
 
 import (
+	"context"
 	"testing"
 
 	"go.uber.org/zap/internal/exit"
@@ -193,6 +194,34 @@ func TestSugarStructuredLogging(t *testing.T) {
 	}
 }
 
+func TestSugarContextLogging(t *testing.T) {
+	type ctxKey struct{}
+	extract := WithContextExtractor(func(ctx context.Context) []Field {
+		if id, ok := ctx.Value(ctxKey{}).(string); ok {
+			return []Field{String("request", id)}
+		}
+		return nil
+	})
+	ctx := context.WithValue(context.Background(), ctxKey{}, "abc")
+
+	withSugar(t, DebugLevel, opts(extract, AddCaller()), func(logger *SugaredLogger, logs *observer.ObservedLogs) {
+		logger.DebugCtx(ctx, "msg", "foo", "bar")
+		logger.InfoCtx(ctx, "msg", "foo", "bar")
+		logger.WarnCtx(ctx, "msg", "foo", "bar")
+		logger.ErrorCtx(ctx, "msg", "foo", "bar")
+		logger.DPanicCtx(ctx, "msg", "foo", "bar")
+
+		levels := []zapcore.Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel, DPanicLevel}
+		output := logs.AllUntimed()
+		require.Equal(t, len(levels), len(output), "Unexpected number of logs written out.")
+		for i, lvl := range levels {
+			assert.Equal(t, lvl, output[i].Level, "Unexpected level.")
+			assert.Equal(t, []Field{String("request", "abc"), String("foo", "bar")}, output[i].Context, "Unexpected context.")
+			assert.Regexp(t, `.+/sugar_test.go:[\d]+$`, output[i].Caller.String(), "Unexpected caller.")
+		}
+	})
+}
+
 func TestSugarConcatenatingLogging(t *testing.T) {
 	tests := []struct {
 		args   []interface{}
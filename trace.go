@@ -0,0 +1,69 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zap
+
+import (
+	"context"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Trace constructs a field that adds the trace_id and span_id of a
+// distributed trace span to the logging context. Adding it to a Logger with
+// With, or to a context with ContextWithFields, also lets samplers built with
+// zapcore.KeepSampledTraces keep all of the span's entries when the trace is
+// sampled. If tc is nil, the field is a no-op.
+func Trace(tc zapcore.TraceContext) Field {
+	if tc == nil {
+		return Skip()
+	}
+	return Inline(traceFields{tc})
+}
+
+// traceFields marshals a TraceContext, and still carries it for samplers.
+type traceFields struct {
+	zapcore.TraceContext
+}
+
+func (tf traceFields) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	if id := tf.TraceID(); id != "" {
+		enc.AddString("trace_id", id)
+	}
+	if id := tf.SpanID(); id != "" {
+		enc.AddString("span_id", id)
+	}
+	return nil
+}
+
+// AddTraceContext configures the Logger's context-aware methods, like
+// InfoCtx, to add the trace_id and span_id of the span that extract finds in
+// the context.Context. extract should return nil if there's no span; it
+// usually adapts a tracing library's span context to zapcore.TraceContext.
+// Only the last extract added this way is used.
+//
+// Unlike other context extractors, extract runs before the entry is checked,
+// and the span is set as the entry's Trace, so samplers built with
+// zapcore.KeepSampledTraces keep all the entries of sampled traces.
+func AddTraceContext(extract func(context.Context) zapcore.TraceContext) Option {
+	return optionFunc(func(log *Logger) {
+		log.extractTrace = extract
+	})
+}
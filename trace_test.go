@@ -0,0 +1,124 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParseTraceparent(t testing.TB, header string) zapcore.TraceContext {
+	tc, err := zapcore.ParseTraceparent(header)
+	require.NoError(t, err, "Unexpected error parsing traceparent.")
+	return tc
+}
+
+func TestTraceField(t *testing.T) {
+	tc := mustParseTraceparent(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	enc := zapcore.NewMapObjectEncoder()
+	Trace(tc).AddTo(enc)
+	assert.Equal(t, map[string]interface{}{
+		"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":  "00f067aa0ba902b7",
+	}, enc.Fields, "Unexpected trace fields.")
+
+	assert.Equal(t, Skip(), Trace(nil), "Expected nil TraceContext to be skipped.")
+}
+
+func TestAddTraceContext(t *testing.T) {
+	type spanKey struct{}
+	tc := mustParseTraceparent(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	extract := AddTraceContext(func(ctx context.Context) zapcore.TraceContext {
+		tc, _ := ctx.Value(spanKey{}).(zapcore.TraceContext)
+		return tc
+	})
+
+	withLogger(t, DebugLevel, opts(extract), func(logger *Logger, logs *observer.ObservedLogs) {
+		logger.InfoCtx(context.WithValue(context.Background(), spanKey{}, tc), "traced")
+		logger.InfoCtx(context.Background(), "untraced")
+
+		output := logs.AllUntimed()
+		require.Equal(t, 2, len(output), "Unexpected number of logs written out.")
+		assert.Equal(t, map[string]interface{}{
+			"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+			"span_id":  "00f067aa0ba902b7",
+		}, output[0].ContextMap(), "Unexpected traced context.")
+		assert.Empty(t, output[1].Context, "Unexpected untraced context.")
+	})
+}
+
+func TestTraceSampling(t *testing.T) {
+	sampled := mustParseTraceparent(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	unsampled := mustParseTraceparent(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+	core, logs := observer.New(DebugLevel)
+	logger := New(zapcore.NewSamplerWithOptions(core, time.Minute, 1, 1000, zapcore.KeepSampledTraces()))
+
+	ctx := ContextWithFields(NewContext(context.Background(), logger), Trace(sampled))
+	for i := 0; i < 5; i++ {
+		FromContext(ctx).Info("sampled trace")
+	}
+	for i := 0; i < 5; i++ {
+		logger.With(Trace(unsampled)).Info("unsampled trace")
+	}
+	assert.Equal(t, 5, logs.FilterMessage("sampled trace").Len(), "Expected all entries of the sampled trace.")
+	assert.Equal(t, 1, logs.FilterMessage("unsampled trace").Len(), "Expected entries of the unsampled trace to be sampled.")
+}
+
+func TestTraceSamplingFromContext(t *testing.T) {
+	type spanKey struct{}
+	sampled := mustParseTraceparent(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	unsampled := mustParseTraceparent(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+	core, logs := observer.New(DebugLevel)
+	logger := New(
+		zapcore.NewSamplerWithOptions(core, time.Minute, 1, 1000, zapcore.KeepSampledTraces()),
+		AddTraceContext(func(ctx context.Context) zapcore.TraceContext {
+			tc, _ := ctx.Value(spanKey{}).(zapcore.TraceContext)
+			return tc
+		}),
+	)
+
+	sampledCtx := context.WithValue(context.Background(), spanKey{}, sampled)
+	unsampledCtx := context.WithValue(context.Background(), spanKey{}, unsampled)
+	for i := 0; i < 5; i++ {
+		logger.InfoCtx(sampledCtx, "sampled trace")
+		logger.Sugar().InfoCtx(sampledCtx, "sampled sugared trace")
+		logger.InfoCtx(unsampledCtx, "unsampled trace")
+	}
+
+	kept := logs.FilterMessage("sampled trace")
+	assert.Equal(t, 5, kept.Len(), "Expected all entries of the sampled trace.")
+	assert.Equal(t, 5, logs.FilterMessage("sampled sugared trace").Len(), "Expected all sugared entries of the sampled trace.")
+	assert.Equal(t, 1, logs.FilterMessage("unsampled trace").Len(), "Expected entries of the unsampled trace to be sampled.")
+	assert.Equal(t, map[string]interface{}{
+		"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":  "00f067aa0ba902b7",
+	}, kept.AllUntimed()[0].ContextMap(), "Expected trace fields on kept entries.")
+}
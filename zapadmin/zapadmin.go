@@ -0,0 +1,391 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package zapadmin provides an HTTP handler for administering loggers while
+// a program is running. It serves JSON endpoints to list named loggers and
+// change their levels, to view and adjust sampling, to view counters of
+// written and dropped entries, and to flush buffered entries.
+//
+// Each endpoint needs the corresponding part of the logging setup: a
+// zap.LevelRegistry for levels, a zapcore.SamplerControl for sampling, and a
+// Syncer, usually the *zap.Logger, for flushing. Endpoints that weren't
+// configured respond with 404 Not Found.
+package zapadmin // import "go.uber.org/zap/zapadmin"
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// A Syncer flushes buffered log entries. *zap.Logger, *zap.SugaredLogger and
+// zapcore.Core are Syncers.
+type Syncer interface {
+	Sync() error
+}
+
+// An Option configures a Handler.
+type Option interface {
+	apply(*Handler)
+}
+
+type optionFunc func(*Handler)
+
+func (f optionFunc) apply(h *Handler) {
+	f(h)
+}
+
+// WithLevels serves the levels of reg at /loggers.
+func WithLevels(reg *zap.LevelRegistry) Option {
+	return optionFunc(func(h *Handler) {
+		h.levels = reg
+	})
+}
+
+// WithSampler serves the sampling parameters and counters of c at
+// /sampling.
+func WithSampler(c *zapcore.SamplerControl) Option {
+	return optionFunc(func(h *Handler) {
+		h.sampler = c
+	})
+}
+
+// WithSyncer flushes s on POST requests to /sync.
+func WithSyncer(s Syncer) Option {
+	return optionFunc(func(h *Handler) {
+		h.syncer = s
+	})
+}
+
+// WithAuthorizer calls authorize before serving each request. If it returns
+// an error, the request is rejected with 403 Forbidden.
+func WithAuthorizer(authorize func(*http.Request) error) Option {
+	return optionFunc(func(h *Handler) {
+		h.authorize = authorize
+	})
+}
+
+// Handler is an http.Handler serving the following JSON endpoints, relative
+// to where it's mounted; use http.StripPrefix to mount it under a prefix.
+//
+// GET /loggers lists the root level, the level rules, and the named loggers
+// seen so far with their effective levels:
+//
+//	{"root":"info","rules":[{"pattern":"db.*","level":"debug"}],
+//	 "loggers":[{"name":"db.pool","level":"debug"}]}
+//
+// PUT /loggers sets the level of loggers matching a pattern, or the root
+// level if the pattern is omitted, and responds like GET:
+//
+//	curl -X PUT localhost:8080/log/loggers -d '{"pattern":"db.*","level":"debug"}'
+//
+// DELETE /loggers?pattern=db.* removes the rule for the pattern.
+//
+// GET /sampling reports the sampling parameters and counters, and PUT
+// /sampling changes any of the parameters:
+//
+//	{"tick":"1s","first":100,"thereafter":100,"sampled":1234,"dropped":56}
+//
+// GET /stats reports the number of entries written at each level, for Cores
+// wrapped with WrapCore, and the sampler's counters:
+//
+//	{"written":{"info":1178,"error":2},"sampled":1234,"dropped":56}
+//
+// POST /sync flushes buffered entries.
+//
+// Errors are reported as {"error":"..."} with an appropriate status code.
+type Handler struct {
+	levels    *zap.LevelRegistry
+	sampler   *zapcore.SamplerControl
+	syncer    Syncer
+	authorize func(*http.Request) error
+
+	written [zapcore.FatalLevel - zapcore.DebugLevel + 1]atomic.Uint64
+}
+
+// NewHandler builds a Handler.
+func NewHandler(opts ...Option) *Handler {
+	h := &Handler{}
+	for _, opt := range opts {
+		opt.apply(h)
+	}
+	return h
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string { return e.err.Error() }
+
+func errorf(status int, format string, args ...interface{}) error {
+	return &httpError{status: status, err: fmt.Errorf(format, args...)}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+
+	resp, err := h.serve(r)
+	if err != nil {
+		status := http.StatusBadRequest
+		var herr *httpError
+		if errors.As(err, &herr) {
+			status = herr.status
+		}
+		w.WriteHeader(status)
+		enc.Encode(errorResponse{Error: err.Error()})
+		return
+	}
+	enc.Encode(resp)
+}
+
+func (h *Handler) serve(r *http.Request) (interface{}, error) {
+	if h.authorize != nil {
+		if err := h.authorize(r); err != nil {
+			return nil, &httpError{status: http.StatusForbidden, err: err}
+		}
+	}
+
+	switch r.URL.Path {
+	case "/loggers", "loggers":
+		return h.serveLoggers(r)
+	case "/sampling", "sampling":
+		return h.serveSampling(r)
+	case "/stats", "stats":
+		return h.serveStats(r)
+	case "/sync", "sync":
+		return h.serveSync(r)
+	default:
+		return nil, errorf(http.StatusNotFound, "unknown endpoint %q", r.URL.Path)
+	}
+}
+
+func methodNotAllowed(methods string) error {
+	return errorf(http.StatusMethodNotAllowed, "only %v are supported", methods)
+}
+
+type levelRule struct {
+	Pattern string        `json:"pattern"`
+	Level   zapcore.Level `json:"level"`
+}
+
+type loggerLevel struct {
+	Name  string        `json:"name"`
+	Level zapcore.Level `json:"level"`
+}
+
+type loggersResponse struct {
+	Root    zapcore.Level `json:"root"`
+	Rules   []levelRule   `json:"rules"`
+	Loggers []loggerLevel `json:"loggers"`
+}
+
+func (h *Handler) serveLoggers(r *http.Request) (interface{}, error) {
+	if h.levels == nil {
+		return nil, errorf(http.StatusNotFound, "levels aren't configured")
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req struct {
+			Pattern string         `json:"pattern"`
+			Level   *zapcore.Level `json:"level"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, fmt.Errorf("malformed request body: %v", err)
+		}
+		if req.Level == nil {
+			return nil, errors.New("must specify logging level")
+		}
+		if req.Pattern == "" {
+			root := h.levels.Root()
+			if root == (zap.AtomicLevel{}) {
+				return nil, errors.New("root level isn't configured")
+			}
+			root.SetLevel(*req.Level)
+		} else if err := h.levels.SetLevel(req.Pattern, *req.Level); err != nil {
+			return nil, err
+		}
+	case http.MethodDelete:
+		pattern := r.URL.Query().Get("pattern")
+		if pattern == "" {
+			return nil, errors.New("must specify pattern")
+		}
+		if !h.levels.UnsetLevel(pattern) {
+			return nil, errorf(http.StatusNotFound, "no rule for pattern %q", pattern)
+		}
+	default:
+		return nil, methodNotAllowed("GET, PUT and DELETE")
+	}
+
+	resp := loggersResponse{
+		Root:    h.levels.Level(""),
+		Rules:   []levelRule{},
+		Loggers: []loggerLevel{},
+	}
+	for _, rule := range h.levels.Rules() {
+		resp.Rules = append(resp.Rules, levelRule{Pattern: rule.Pattern, Level: rule.Level})
+	}
+	for _, name := range h.levels.Names() {
+		resp.Loggers = append(resp.Loggers, loggerLevel{Name: name, Level: h.levels.Level(name)})
+	}
+	return resp, nil
+}
+
+type samplingResponse struct {
+	Tick       string `json:"tick"`
+	First      int    `json:"first"`
+	Thereafter int    `json:"thereafter"`
+	Sampled    uint64 `json:"sampled"`
+	Dropped    uint64 `json:"dropped"`
+}
+
+func (h *Handler) serveSampling(r *http.Request) (interface{}, error) {
+	if h.sampler == nil {
+		return nil, errorf(http.StatusNotFound, "sampling isn't configured")
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req struct {
+			Tick       *string `json:"tick"`
+			First      *int    `json:"first"`
+			Thereafter *int    `json:"thereafter"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, fmt.Errorf("malformed request body: %v", err)
+		}
+
+		tick, first, thereafter := h.sampler.Params()
+		if req.Tick != nil {
+			d, err := time.ParseDuration(*req.Tick)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tick: %v", err)
+			}
+			tick = d
+		}
+		if req.First != nil {
+			first = *req.First
+		}
+		if req.Thereafter != nil {
+			thereafter = *req.Thereafter
+		}
+		if err := h.sampler.SetParams(tick, first, thereafter); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, methodNotAllowed("GET and PUT")
+	}
+
+	tick, first, thereafter := h.sampler.Params()
+	return samplingResponse{
+		Tick:       tick.String(),
+		First:      first,
+		Thereafter: thereafter,
+		Sampled:    h.sampler.Sampled(),
+		Dropped:    h.sampler.Dropped(),
+	}, nil
+}
+
+type statsResponse struct {
+	Written map[string]uint64 `json:"written"`
+	Sampled *uint64           `json:"sampled,omitempty"`
+	Dropped *uint64           `json:"dropped,omitempty"`
+}
+
+func (h *Handler) serveStats(r *http.Request) (interface{}, error) {
+	if r.Method != http.MethodGet {
+		return nil, methodNotAllowed("GET")
+	}
+
+	resp := statsResponse{Written: make(map[string]uint64)}
+	for i := range h.written {
+		if n := h.written[i].Load(); n > 0 {
+			resp.Written[(zapcore.DebugLevel + zapcore.Level(i)).String()] = n
+		}
+	}
+	if h.sampler != nil {
+		sampled, dropped := h.sampler.Sampled(), h.sampler.Dropped()
+		resp.Sampled, resp.Dropped = &sampled, &dropped
+	}
+	return resp, nil
+}
+
+func (h *Handler) serveSync(r *http.Request) (interface{}, error) {
+	if h.syncer == nil {
+		return nil, errorf(http.StatusNotFound, "sync isn't configured")
+	}
+	if r.Method != http.MethodPost {
+		return nil, methodNotAllowed("POST")
+	}
+	if err := h.syncer.Sync(); err != nil {
+		return nil, errorf(http.StatusInternalServerError, "sync failed: %v", err)
+	}
+	return struct {
+		Synced bool `json:"synced"`
+	}{true}, nil
+}
+
+// WrapCore wraps core to count the entries it writes at each level, as
+// reported by /stats. Use it with the zap.WrapCore option.
+func (h *Handler) WrapCore(core zapcore.Core) zapcore.Core {
+	return &countingCore{Core: core, h: h}
+}
+
+type countingCore struct {
+	zapcore.Core
+
+	h *Handler
+}
+
+func (c *countingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &countingCore{Core: c.Core.With(fields), h: c.h}
+}
+
+func (c *countingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// Like zapcore.RegisterHooks, let the wrapped Core register itself
+	// directly, and only count the entry when it's written.
+	if downstream := c.Core.Check(ent, ce); downstream != nil {
+		return downstream.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *countingCore) Write(ent zapcore.Entry, _ []zapcore.Field) error {
+	if ent.Level >= zapcore.DebugLevel && ent.Level <= zapcore.FatalLevel {
+		c.h.written[ent.Level-zapcore.DebugLevel].Inc()
+	}
+	return nil
+}
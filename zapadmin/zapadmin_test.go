@@ -0,0 +1,191 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapadmin
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSyncer struct {
+	err    error
+	called bool
+}
+
+func (s *fakeSyncer) Sync() error {
+	s.called = true
+	return s.err
+}
+
+func do(t testing.TB, srv *httptest.Server, method, path, body string) (int, string) {
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	require.NoError(t, err, "Error constructing %s request.", method)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err, "Error making %s request.", method)
+	defer res.Body.Close()
+	out, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err, "Error reading response body.")
+	return res.StatusCode, strings.TrimSpace(string(out))
+}
+
+func TestHandlerLoggers(t *testing.T) {
+	root := zap.NewAtomicLevelAt(zap.InfoLevel)
+	reg := zap.NewLevelRegistry(root)
+	core, _ := observer.New(reg)
+	logger := zap.New(core, zap.NamedLevels(reg))
+	logger.Named("db").Named("pool").Info("seen")
+	logger.Named("http").Info("seen")
+
+	srv := httptest.NewServer(NewHandler(WithLevels(reg)))
+	defer srv.Close()
+
+	code, body := do(t, srv, http.MethodGet, "/loggers", "")
+	assert.Equal(t, http.StatusOK, code, "Unexpected status.")
+	assert.JSONEq(t, `{"root":"info","rules":[],"loggers":[
+		{"name":"db.pool","level":"info"},{"name":"http","level":"info"}]}`, body, "Unexpected response.")
+
+	code, body = do(t, srv, http.MethodPut, "/loggers", `{"pattern":"db.*","level":"debug"}`)
+	assert.Equal(t, http.StatusOK, code, "Unexpected status.")
+	assert.JSONEq(t, `{"root":"info","rules":[{"pattern":"db.*","level":"debug"}],"loggers":[
+		{"name":"db.pool","level":"debug"},{"name":"http","level":"info"}]}`, body, "Unexpected response.")
+
+	code, _ = do(t, srv, http.MethodPut, "/loggers", `{"level":"warn"}`)
+	assert.Equal(t, http.StatusOK, code, "Unexpected status.")
+	assert.Equal(t, zap.WarnLevel, root.Level(), "Expected root level to change.")
+
+	code, body = do(t, srv, http.MethodDelete, "/loggers?pattern=db.*", "")
+	assert.Equal(t, http.StatusOK, code, "Unexpected status.")
+	assert.JSONEq(t, `{"root":"warn","rules":[],"loggers":[
+		{"name":"db.pool","level":"warn"},{"name":"http","level":"warn"}]}`, body, "Unexpected response.")
+
+	tests := []struct {
+		method, path, body string
+		code               int
+		err                string
+	}{
+		{http.MethodDelete, "/loggers?pattern=db.*", "", http.StatusNotFound, `no rule for pattern "db.*"`},
+		{http.MethodDelete, "/loggers", "", http.StatusBadRequest, "must specify pattern"},
+		{http.MethodPut, "/loggers", `{"pattern":"db"}`, http.StatusBadRequest, "must specify logging level"},
+		{http.MethodPut, "/loggers", `{"pattern":"db.","level":"info"}`, http.StatusBadRequest, `invalid level rule pattern "db.": empty name part`},
+		{http.MethodPut, "/loggers", `{`, http.StatusBadRequest, "malformed request body: unexpected EOF"},
+		{http.MethodPost, "/loggers", "", http.StatusMethodNotAllowed, "only GET, PUT and DELETE are supported"},
+		{http.MethodGet, "/sampling", "", http.StatusNotFound, "sampling isn't configured"},
+		{http.MethodPost, "/sync", "", http.StatusNotFound, "sync isn't configured"},
+		{http.MethodGet, "/unknown", "", http.StatusNotFound, `unknown endpoint "/unknown"`},
+	}
+	for _, tt := range tests {
+		code, body := do(t, srv, tt.method, tt.path, tt.body)
+		assert.Equal(t, tt.code, code, "Unexpected status for %v %v.", tt.method, tt.path)
+		assert.JSONEq(t, `{"error":"`+strings.Replace(tt.err, `"`, `\"`, -1)+`"}`, body, "Unexpected error for %v %v.", tt.method, tt.path)
+	}
+}
+
+func TestHandlerSamplingAndStats(t *testing.T) {
+	control := zapcore.NewSamplerControl()
+	h := NewHandler(WithSampler(control))
+	core, _ := observer.New(zap.DebugLevel)
+	logger := zap.New(zapcore.NewSamplerWithOptions(h.WrapCore(core), time.Minute, 2, 100, zapcore.SamplerControls(control)))
+	for i := 0; i < 5; i++ {
+		logger.Info("sampled")
+	}
+	logger.Error("error")
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	code, body := do(t, srv, http.MethodGet, "/sampling", "")
+	assert.Equal(t, http.StatusOK, code, "Unexpected status.")
+	assert.JSONEq(t, `{"tick":"1m0s","first":2,"thereafter":100,"sampled":3,"dropped":3}`, body, "Unexpected response.")
+
+	code, body = do(t, srv, http.MethodGet, "/stats", "")
+	assert.Equal(t, http.StatusOK, code, "Unexpected status.")
+	assert.JSONEq(t, `{"written":{"info":2,"error":1},"sampled":3,"dropped":3}`, body, "Unexpected response.")
+
+	code, body = do(t, srv, http.MethodPut, "/sampling", `{"tick":"1s","thereafter":10}`)
+	assert.Equal(t, http.StatusOK, code, "Unexpected status.")
+	assert.JSONEq(t, `{"tick":"1s","first":2,"thereafter":10,"sampled":3,"dropped":3}`, body, "Unexpected response.")
+
+	code, body = do(t, srv, http.MethodPut, "/sampling", `{"thereafter":0}`)
+	assert.Equal(t, http.StatusBadRequest, code, "Unexpected status.")
+	assert.Contains(t, body, "invalid sampling parameters", "Unexpected response.")
+
+	code, body = do(t, srv, http.MethodPut, "/sampling", `{"tick":"soon"}`)
+	assert.Equal(t, http.StatusBadRequest, code, "Unexpected status.")
+	assert.Contains(t, body, "invalid tick", "Unexpected response.")
+}
+
+func TestHandlerSync(t *testing.T) {
+	syncer := &fakeSyncer{}
+	srv := httptest.NewServer(NewHandler(WithSyncer(syncer)))
+	defer srv.Close()
+
+	code, _ := do(t, srv, http.MethodGet, "/sync", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code, "Unexpected status.")
+	assert.False(t, syncer.called, "Unexpected sync on GET.")
+
+	code, body := do(t, srv, http.MethodPost, "/sync", "")
+	assert.Equal(t, http.StatusOK, code, "Unexpected status.")
+	assert.JSONEq(t, `{"synced":true}`, body, "Unexpected response.")
+	assert.True(t, syncer.called, "Expected sync.")
+
+	syncer.err = errors.New("disk full")
+	code, body = do(t, srv, http.MethodPost, "/sync", "")
+	assert.Equal(t, http.StatusInternalServerError, code, "Unexpected status.")
+	assert.JSONEq(t, `{"error":"sync failed: disk full"}`, body, "Unexpected response.")
+}
+
+func TestHandlerAuthorizer(t *testing.T) {
+	syncer := &fakeSyncer{}
+	h := NewHandler(WithSyncer(syncer), WithAuthorizer(func(r *http.Request) error {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			return errors.New("not authorized")
+		}
+		return nil
+	}))
+	srv := httptest.NewServer(http.StripPrefix("/admin", h))
+	defer srv.Close()
+
+	code, body := do(t, srv, http.MethodPost, "/admin/sync", "")
+	assert.Equal(t, http.StatusForbidden, code, "Unexpected status.")
+	assert.JSONEq(t, `{"error":"not authorized"}`, body, "Unexpected response.")
+	assert.False(t, syncer.called, "Unexpected sync without authorization.")
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/admin/sync", nil)
+	require.NoError(t, err, "Error constructing request.")
+	req.Header.Set("Authorization", "Bearer secret")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err, "Error making request.")
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode, "Unexpected status.")
+	assert.True(t, syncer.called, "Expected sync with authorization.")
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/internal/bufferpool"
+)
+
+var (
+	_mainModuleOnce sync.Once
+	_mainModule     string
+)
+
+// mainModule returns the path of the main module, or an empty string if the
+// binary wasn't built with module support.
+func mainModule() string {
+	_mainModuleOnce.Do(func() {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			_mainModule = info.Main.Path
+		}
+	})
+	return _mainModule
+}
+
+// ModuleCallerEncoder serializes a caller in path/to/package/file:line
+// format, where the path is relative to the main module, as reported by
+// runtime/debug.ReadBuildInfo. Callers outside the main module keep their
+// full import path, like go.uber.org/zap/logger.go:42. Unlike
+// FullCallerEncoder, the output never includes the directory the binary was
+// built in.
+func ModuleCallerEncoder(caller EntryCaller, enc PrimitiveArrayEncoder) {
+	encodeModuleRelative(caller, mainModule(), enc)
+}
+
+// CallerEncoderRelativeTo returns a CallerEncoder which works like
+// ModuleCallerEncoder, but treats the module with the given path as the main
+// module.
+func CallerEncoderRelativeTo(modulePath string) CallerEncoder {
+	return func(caller EntryCaller, enc PrimitiveArrayEncoder) {
+		encodeModuleRelative(caller, modulePath, enc)
+	}
+}
+
+// TrailingCallerEncoder returns a CallerEncoder which serializes a caller in
+// file:line format, keeping the final n segments of the file's path. With
+// n = 2, it's equivalent to ShortCallerEncoder. Values of n less than one
+// are treated as one.
+func TrailingCallerEncoder(n int) CallerEncoder {
+	if n < 1 {
+		n = 1
+	}
+	return func(caller EntryCaller, enc PrimitiveArrayEncoder) {
+		if !caller.Defined {
+			enc.AppendString("undefined")
+			return
+		}
+		// As in EntryCaller.TrimmedPath, paths from the runtime use '/' on
+		// all platforms.
+		file := caller.File
+		idx := len(file)
+		for i := 0; i < n && idx >= 0; i++ {
+			idx = strings.LastIndexByte(file[:idx], '/')
+		}
+		appendCallerPath(enc, file[idx+1:], caller.Line)
+	}
+}
+
+// FunctionCallerEncoder serializes a caller in pkg.Func:line format, using
+// the final element of the function's package path, like
+// zap.(*Logger).Info:42. If the function isn't known, it falls back to
+// ShortCallerEncoder.
+func FunctionCallerEncoder(caller EntryCaller, enc PrimitiveArrayEncoder) {
+	fn := callerFunction(caller)
+	if fn == "" {
+		ShortCallerEncoder(caller, enc)
+		return
+	}
+	if idx := strings.LastIndexByte(fn, '/'); idx >= 0 {
+		fn = fn[idx+1:]
+	}
+	appendCallerPath(enc, strings.Replace(fn, "%2e", ".", -1), caller.Line)
+}
+
+// ObjectCallerEncoder serializes a caller as an object with "file", "line",
+// and "function" keys, omitting the function if it isn't known. This lets
+// tools index entries by file and line without parsing strings.
+//
+// Only encoders that can append objects, like the JSON encoder, support this
+// form; others get a string as from FullCallerEncoder. In particular, the
+// console encoder always writes a string.
+func ObjectCallerEncoder(caller EntryCaller, enc PrimitiveArrayEncoder) {
+	type appendObjectEncoder interface {
+		AppendObject(ObjectMarshaler) error
+	}
+
+	if enc, ok := enc.(appendObjectEncoder); ok && caller.Defined {
+		_ = enc.AppendObject(callerObject(caller))
+		return
+	}
+	FullCallerEncoder(caller, enc)
+}
+
+type callerObject EntryCaller
+
+func (c callerObject) MarshalLogObject(enc ObjectEncoder) error {
+	enc.AddString("file", c.File)
+	enc.AddInt("line", c.Line)
+	if c.Function != "" {
+		enc.AddString("function", c.Function)
+	}
+	return nil
+}
+
+// primitiveArrayEncoder hides any methods beyond those of
+// PrimitiveArrayEncoder from the encoder it wraps, so that encoders which
+// check for optional methods fall back to primitives.
+type primitiveArrayEncoder struct {
+	PrimitiveArrayEncoder
+}
+
+// unmarshalTrailingCallerEncoder parses names like "trailing:3" into
+// TrailingCallerEncoders.
+func unmarshalTrailingCallerEncoder(text string) (CallerEncoder, bool) {
+	const prefix = "trailing:"
+	if !strings.HasPrefix(text, prefix) {
+		return nil, false
+	}
+	n, err := strconv.Atoi(text[len(prefix):])
+	if err != nil || n < 1 {
+		return nil, false
+	}
+	return TrailingCallerEncoder(n), true
+}
+
+func appendCallerPath(enc PrimitiveArrayEncoder, path string, line int) {
+	buf := bufferpool.Get()
+	buf.AppendString(path)
+	buf.AppendByte(':')
+	buf.AppendInt(int64(line))
+	enc.AppendString(buf.String())
+	buf.Free()
+}
+
+func callerFunction(caller EntryCaller) string {
+	if caller.Function != "" {
+		return caller.Function
+	}
+	if fn := runtime.FuncForPC(caller.PC); caller.PC != 0 && fn != nil {
+		return fn.Name()
+	}
+	return ""
+}
+
+// callerPackage returns the import path of the caller's package, or an empty
+// string if it isn't known.
+func callerPackage(caller EntryCaller) string {
+	fn := callerFunction(caller)
+	// Function names look like go.uber.org/zap.(*Logger).Info. Dots in the
+	// package's final path element are escaped, as in gopkg.in/yaml%2ev2.
+	slash := strings.LastIndexByte(fn, '/')
+	dot := strings.IndexByte(fn[slash+1:], '.')
+	if dot < 0 {
+		return ""
+	}
+	return strings.Replace(fn[:slash+1+dot], "%2e", ".", -1)
+}
+
+func encodeModuleRelative(caller EntryCaller, modulePath string, enc PrimitiveArrayEncoder) {
+	if !caller.Defined {
+		ShortCallerEncoder(caller, enc)
+		return
+	}
+
+	path := caller.File
+	trimmed := modulePath != "" && strings.HasPrefix(path, modulePath+"/")
+	if !trimmed {
+		// Not built with -trimpath, so replace the directory with the
+		// package's import path.
+		pkg := callerPackage(caller)
+		if pkg == "" || pkg == "main" {
+			ShortCallerEncoder(caller, enc)
+			return
+		}
+		path = pkg + "/" + path[strings.LastIndexByte(path, '/')+1:]
+	}
+	if modulePath != "" {
+		path = strings.TrimPrefix(path, modulePath+"/")
+	}
+	appendCallerPath(enc, path, caller.Line)
+}
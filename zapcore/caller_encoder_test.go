@@ -0,0 +1,167 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore_test
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "go.uber.org/zap/zapcore"
+)
+
+func TestModuleRelativeCallerEncoder(t *testing.T) {
+	enc := CallerEncoderRelativeTo("github.com/acme/svc")
+	tests := []struct {
+		desc     string
+		caller   EntryCaller
+		expected string
+	}{
+		{
+			desc: "in module",
+			caller: EntryCaller{
+				Defined:  true,
+				File:     "/builds/ci-1234/svc/internal/db/conn.go",
+				Line:     12,
+				Function: "github.com/acme/svc/internal/db.(*Conn).Query",
+			},
+			expected: "internal/db/conn.go:12",
+		},
+		{
+			desc: "in module with trimpath",
+			caller: EntryCaller{
+				Defined:  true,
+				File:     "github.com/acme/svc/internal/db/conn.go",
+				Line:     12,
+				Function: "github.com/acme/svc/internal/db.Open",
+			},
+			expected: "internal/db/conn.go:12",
+		},
+		{
+			desc: "dependency",
+			caller: EntryCaller{
+				Defined:  true,
+				File:     "/root/go/pkg/mod/go.uber.org/zap@v1.0.0/logger.go",
+				Line:     7,
+				Function: "go.uber.org/zap.(*Logger).Info",
+			},
+			expected: "go.uber.org/zap/logger.go:7",
+		},
+		{
+			desc: "dotted package name",
+			caller: EntryCaller{
+				Defined:  true,
+				File:     "/src/gopkg.in/yaml.v2/decode.go",
+				Line:     3,
+				Function: "gopkg.in/yaml%2ev2.Unmarshal",
+			},
+			expected: "gopkg.in/yaml.v2/decode.go:3",
+		},
+		{
+			desc: "main package",
+			caller: EntryCaller{
+				Defined:  true,
+				File:     "/builds/ci-1234/svc/cmd/svc/main.go",
+				Line:     5,
+				Function: "main.main",
+			},
+			expected: "svc/main.go:5",
+		},
+		{
+			desc:     "unknown function",
+			caller:   EntryCaller{Defined: true, File: "/builds/svc/main.go", Line: 5},
+			expected: "svc/main.go:5",
+		},
+		{
+			desc:     "undefined",
+			caller:   EntryCaller{},
+			expected: "undefined",
+		},
+	}
+
+	for _, tt := range tests {
+		assertAppended(t, tt.expected, func(arr ArrayEncoder) { enc(tt.caller, arr) }, "Unexpected output for %s.", tt.desc)
+	}
+}
+
+func TestCallerEncodersFunctionFromPC(t *testing.T) {
+	pc, file, line, ok := runtime.Caller(0)
+	caller := NewEntryCaller(pc, file, line, ok)
+
+	assertAppended(t, "zapcore_test.TestCallerEncodersFunctionFromPC:"+strconv.Itoa(line), func(arr ArrayEncoder) {
+		FunctionCallerEncoder(caller, arr)
+	}, "Expected function to be looked up from the PC.")
+	assertAppended(t, "go.uber.org/zap/zapcore_test/caller_encoder_test.go:"+strconv.Itoa(line), func(arr ArrayEncoder) {
+		CallerEncoderRelativeTo("")(caller, arr)
+	}, "Expected package path to be looked up from the PC.")
+	assertAppended(t, "zapcore_test/caller_encoder_test.go:"+strconv.Itoa(line), func(arr ArrayEncoder) {
+		CallerEncoderRelativeTo("go.uber.org/zap")(caller, arr)
+	}, "Expected path relative to the module.")
+}
+
+func TestObjectCallerEncoder(t *testing.T) {
+	var ce CallerEncoder
+	require.NoError(t, ce.UnmarshalText([]byte("object")), "Unexpected error unmarshaling caller encoder.")
+
+	cfg := EncoderConfig{
+		MessageKey:   "M",
+		CallerKey:    "C",
+		EncodeCaller: ce,
+	}
+	ent := Entry{Message: "hello"}
+
+	tests := []struct {
+		desc     string
+		enc      Encoder
+		caller   EntryCaller
+		expected string
+	}{
+		{
+			desc:     "JSON",
+			enc:      NewJSONEncoder(cfg),
+			caller:   EntryCaller{Defined: true, File: "/src/foo/foo.go", Line: 42, Function: "foo.Bar"},
+			expected: `{"C":{"file":"/src/foo/foo.go","line":42,"function":"foo.Bar"},"M":"hello"}`,
+		},
+		{
+			desc:     "JSON without function",
+			enc:      NewJSONEncoder(cfg),
+			caller:   EntryCaller{Defined: true, File: "/src/foo/foo.go", Line: 42},
+			expected: `{"C":{"file":"/src/foo/foo.go","line":42},"M":"hello"}`,
+		},
+		{
+			desc:     "console",
+			enc:      NewConsoleEncoder(cfg),
+			caller:   EntryCaller{Defined: true, File: "/src/foo/foo.go", Line: 42, Function: "foo.Bar"},
+			expected: "/src/foo/foo.go:42\thello",
+		},
+	}
+
+	for _, tt := range tests {
+		ent.Caller = tt.caller
+		buf, err := tt.enc.EncodeEntry(ent, nil)
+		require.NoError(t, err, "Unexpected error encoding entry.")
+		assert.Equal(t, tt.expected+"\n", buf.String(), "Unexpected output for %s.", tt.desc)
+		buf.Free()
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ColorMode controls whether the color level encoders (LowercaseColorLevelEncoder
+// and CapitalColorLevelEncoder) add ANSI escape sequences to their output.
+type ColorMode uint8
+
+const (
+	// ColorAlways always adds color. It's the zero value, which preserves the
+	// behavior of configurations that predate ColorMode.
+	ColorAlways ColorMode = iota
+	// ColorAuto adds color only when the log destination is a terminal. If the
+	// NO_COLOR environment variable is set to a non-empty value, color is
+	// disabled; otherwise, if FORCE_COLOR is set to a non-empty value other
+	// than "0" or "false", color is enabled regardless of the destination.
+	//
+	// Encoders don't know where their output is written, so an encoder
+	// constructed directly with ColorAuto honors only the environment
+	// variables. Use ColorMode.Enabled to resolve ColorAuto against a
+	// particular WriteSyncer; zap.Config does this automatically.
+	ColorAuto
+	// ColorNever never adds color.
+	ColorNever
+)
+
+// String returns a lower-case ASCII representation of the color mode.
+func (m ColorMode) String() string {
+	switch m {
+	case ColorAlways:
+		return "always"
+	case ColorAuto:
+		return "auto"
+	case ColorNever:
+		return "never"
+	default:
+		return fmt.Sprintf("ColorMode(%d)", m)
+	}
+}
+
+// MarshalText marshals the ColorMode to text.
+func (m ColorMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText unmarshals text to a ColorMode. "always" and the empty string
+// are unmarshaled to ColorAlways, "auto" to ColorAuto, and "never" to
+// ColorNever. Matching is case-insensitive.
+func (m *ColorMode) UnmarshalText(text []byte) error {
+	switch string(bytes.ToLower(text)) {
+	case "always", "":
+		*m = ColorAlways
+	case "auto":
+		*m = ColorAuto
+	case "never":
+		*m = ColorNever
+	default:
+		return fmt.Errorf("unrecognized color mode: %q", text)
+	}
+	return nil
+}
+
+// Enabled reports whether color should be added to logs written to w. It
+// always returns true for ColorAlways and false for ColorNever. For
+// ColorAuto, the NO_COLOR and FORCE_COLOR environment variables take
+// precedence; otherwise, color is enabled only if w writes to a terminal.
+//
+// Terminal detection sees through the WriteSyncers provided by this package
+// (Lock, AddSync, NewMultiWriteSyncer, and BufferedWriteSyncer). A
+// WriteSyncer that fans out to several destinations is considered a terminal
+// only if all of them are.
+func (m ColorMode) Enabled(w io.Writer) bool {
+	switch m {
+	case ColorNever:
+		return false
+	case ColorAuto:
+		if enabled, ok := colorFromEnv(); ok {
+			return enabled
+		}
+		return isTerminal(w)
+	default:
+		return true
+	}
+}
+
+// withEnv resolves ColorAuto using only the environment, defaulting to
+// ColorAlways. Other modes are returned unchanged.
+func (m ColorMode) withEnv() ColorMode {
+	if m != ColorAuto {
+		return m
+	}
+	if enabled, ok := colorFromEnv(); ok && !enabled {
+		return ColorNever
+	}
+	return ColorAlways
+}
+
+// colorFromEnv reports whether the NO_COLOR or FORCE_COLOR environment
+// variables request a particular behavior. The boolean ok is false if
+// neither is set.
+func colorFromEnv() (enabled bool, ok bool) {
+	if os.Getenv("NO_COLOR") != "" {
+		return false, true
+	}
+	switch os.Getenv("FORCE_COLOR") {
+	case "", "0", "false":
+		return false, false
+	}
+	return true, true
+}
+
+func isTerminal(w io.Writer) bool {
+	switch w := w.(type) {
+	case *os.File:
+		fi, err := w.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice != 0
+	case *lockedWriteSyncer:
+		return isTerminal(w.ws)
+	case writerWrapper:
+		return isTerminal(w.Writer)
+	case *BufferedWriteSyncer:
+		return isTerminal(w.WS)
+	case multiWriteSyncer:
+		for _, ws := range w {
+			if !isTerminal(ws) {
+				return false
+			}
+		}
+		return len(w) > 0
+	default:
+		return false
+	}
+}
+
+// colorDisabled reports whether enc asks the color level encoders to omit
+// escape sequences.
+func colorDisabled(enc PrimitiveArrayEncoder) bool {
+	type colorModeEncoder interface {
+		colorDisabled() bool
+	}
+
+	if enc, ok := enc.(colorModeEncoder); ok {
+		return enc.colorDisabled()
+	}
+	return false
+}
+
+// uncoloredArrayEncoder wraps a PrimitiveArrayEncoder and asks the color
+// level encoders to omit escape sequences.
+type uncoloredArrayEncoder struct {
+	PrimitiveArrayEncoder
+}
+
+func (uncoloredArrayEncoder) colorDisabled() bool { return true }
@@ -0,0 +1,183 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withColorEnv(t testing.TB, noColor, forceColor string) func() {
+	setenv := func(key, value string) func() {
+		prev, ok := os.LookupEnv(key)
+		require.NoError(t, os.Setenv(key, value), "Failed to set %v.", key)
+		return func() {
+			if ok {
+				os.Setenv(key, prev)
+			} else {
+				os.Unsetenv(key)
+			}
+		}
+	}
+	restoreNoColor := setenv("NO_COLOR", noColor)
+	restoreForceColor := setenv("FORCE_COLOR", forceColor)
+	return func() {
+		restoreForceColor()
+		restoreNoColor()
+	}
+}
+
+func TestColorModeText(t *testing.T) {
+	tests := []struct {
+		text string
+		mode ColorMode
+	}{
+		{"always", ColorAlways},
+		{"auto", ColorAuto},
+		{"never", ColorNever},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			text, err := tt.mode.MarshalText()
+			require.NoError(t, err, "Unexpected error marshaling %v.", tt.mode)
+			assert.Equal(t, tt.text, string(text), "Unexpected text for %v.", tt.mode)
+
+			var mode ColorMode
+			require.NoError(t, mode.UnmarshalText([]byte(tt.text)), "Unexpected error unmarshaling %q.", tt.text)
+			assert.Equal(t, tt.mode, mode, "Unexpected mode after round-trip.")
+		})
+	}
+
+	var mode ColorMode
+	require.NoError(t, mode.UnmarshalText([]byte("AUTO")), "Expected matching to be case-insensitive.")
+	assert.Equal(t, ColorAuto, mode, "Unexpected mode unmarshaling upper-case text.")
+	assert.Error(t, mode.UnmarshalText([]byte("sometimes")), "Expected error unmarshaling unknown mode.")
+	assert.Equal(t, "ColorMode(42)", ColorMode(42).String(), "Unexpected string for unknown mode.")
+}
+
+func TestColorModeEnabled(t *testing.T) {
+	tests := []struct {
+		desc       string
+		mode       ColorMode
+		noColor    string
+		forceColor string
+		want       bool
+	}{
+		{desc: "always", mode: ColorAlways, noColor: "1", want: true},
+		{desc: "never", mode: ColorNever, forceColor: "1", want: false},
+		{desc: "auto without terminal", mode: ColorAuto, want: false},
+		{desc: "auto with FORCE_COLOR", mode: ColorAuto, forceColor: "1", want: true},
+		{desc: "auto with FORCE_COLOR=0", mode: ColorAuto, forceColor: "0", want: false},
+		{desc: "auto with NO_COLOR and FORCE_COLOR", mode: ColorAuto, noColor: "1", forceColor: "1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			defer withColorEnv(t, tt.noColor, tt.forceColor)()
+			assert.Equal(t, tt.want, tt.mode.Enabled(&bytes.Buffer{}), "Unexpected result from Enabled.")
+		})
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	f, err := ioutil.TempFile("", "zap-color-test")
+	require.NoError(t, err, "Failed to create temporary file.")
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	tests := []struct {
+		desc string
+		w    WriteSyncer
+	}{
+		{"regular file", f},
+		{"locked file", Lock(f)},
+		{"buffered file", &BufferedWriteSyncer{WS: f}},
+		{"wrapped buffer", AddSync(&bytes.Buffer{})},
+		{"multiple outputs", NewMultiWriteSyncer(f, AddSync(&bytes.Buffer{}))},
+		{"no outputs", NewMultiWriteSyncer()},
+	}
+
+	for _, tt := range tests {
+		assert.False(t, isTerminal(tt.w), "Expected %v not to be a terminal.", tt.desc)
+	}
+}
+
+func TestColorLevelEncodersRespectColorMode(t *testing.T) {
+	cfg := EncoderConfig{
+		LevelKey:    "L",
+		MessageKey:  "M",
+		EncodeLevel: CapitalColorLevelEncoder,
+	}
+	ent := Entry{Level: InfoLevel, Message: "hello"}
+
+	tests := []struct {
+		desc            string
+		mode            ColorMode
+		noColor         string
+		expectedJSON    string
+		expectedConsole string
+	}{
+		{
+			desc:            "always",
+			mode:            ColorAlways,
+			expectedJSON:    `{"L":"\u001b[34mINFO\u001b[0m","M":"hello"}` + "\n",
+			expectedConsole: "\x1b[34mINFO\x1b[0m\thello\n",
+		},
+		{
+			desc:            "never",
+			mode:            ColorNever,
+			expectedJSON:    `{"L":"INFO","M":"hello"}` + "\n",
+			expectedConsole: "INFO\thello\n",
+		},
+		{
+			desc:            "auto with NO_COLOR",
+			mode:            ColorAuto,
+			noColor:         "1",
+			expectedJSON:    `{"L":"INFO","M":"hello"}` + "\n",
+			expectedConsole: "INFO\thello\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			defer withColorEnv(t, tt.noColor, "")()
+
+			cfg := cfg
+			cfg.ColorMode = tt.mode
+
+			buf, err := NewJSONEncoder(cfg).EncodeEntry(ent, nil)
+			require.NoError(t, err, "Unexpected error encoding JSON entry.")
+			assert.Equal(t, tt.expectedJSON, buf.String(), "Unexpected JSON output.")
+			buf.Free()
+
+			buf, err = NewConsoleEncoder(cfg).EncodeEntry(ent, nil)
+			require.NoError(t, err, "Unexpected error encoding console entry.")
+			assert.Equal(t, tt.expectedConsole, buf.String(), "Unexpected console output.")
+			buf.Free()
+		})
+	}
+}
@@ -68,6 +68,12 @@ func (c consoleEncoder) Clone() Encoder {
 }
 
 func (c consoleEncoder) EncodeEntry(ent Entry, fields []Field) (*buffer.Buffer, error) {
+	line, duplicates, err := c.encodeEntry(ent, fields)
+	c.reportDuplicateKeys(duplicates)
+	return line, err
+}
+
+func (c consoleEncoder) encodeEntry(ent Entry, fields []Field) (*buffer.Buffer, []string, error) {
 	line := bufferpool.Get()
 
 	// We don't want the entry's metadata to be quoted and escaped (if it's
@@ -78,10 +84,14 @@ func (c consoleEncoder) EncodeEntry(ent Entry, fields []Field) (*buffer.Buffer,
 	// ArrayEncoder for our plain-text format.
 	arr := getSliceEncoder()
 	if c.TimeKey != "" && c.EncodeTime != nil {
-		c.EncodeTime(ent.Time, arr)
+		c.EncodeTime(c.TimeZone.In(ent.Time), arr)
 	}
 	if c.LevelKey != "" && c.EncodeLevel != nil {
-		c.EncodeLevel(ent.Level, arr)
+		if c.colorDisabled() {
+			c.EncodeLevel(ent.Level, uncoloredArrayEncoder{arr})
+		} else {
+			c.EncodeLevel(ent.Level, arr)
+		}
 	}
 	if ent.LoggerName != "" && c.NameKey != "" {
 		nameEncoder := c.EncodeName
@@ -95,7 +105,8 @@ func (c consoleEncoder) EncodeEntry(ent Entry, fields []Field) (*buffer.Buffer,
 	}
 	if ent.Caller.Defined {
 		if c.CallerKey != "" && c.EncodeCaller != nil {
-			c.EncodeCaller(ent.Caller, arr)
+			// The console format has no room for structured callers.
+			c.EncodeCaller(ent.Caller, primitiveArrayEncoder{arr})
 		}
 		if c.FunctionKey != "" {
 			arr.AppendString(ent.Caller.Function)
@@ -112,28 +123,54 @@ func (c consoleEncoder) EncodeEntry(ent Entry, fields []Field) (*buffer.Buffer,
 	// Add the message itself.
 	if c.MessageKey != "" {
 		c.addSeparatorIfNecessary(line)
-		line.AppendString(ent.Message)
+		msg, removed := truncateString(ent.Message, c.MaxStringLength)
+		line.AppendString(msg)
+		if removed > 0 {
+			appendTruncationMarker(line, removed)
+		}
 	}
 
 	// Add any structured context.
-	c.writeContext(line, fields)
+	duplicates := c.writeContext(line, fields)
 
 	// If there's no stacktrace key, honor that; this allows users to force
 	// single-line output.
 	if ent.Stack != "" && c.StacktraceKey != "" {
 		line.AppendByte('\n')
-		line.AppendString(ent.Stack)
+		stack, removed := truncateString(c.encodeStacktrace(ent.Stack), c.MaxStringLength)
+		line.AppendString(stack)
+		if removed > 0 {
+			appendTruncationMarker(line, removed)
+		}
+	}
+
+	lineEnding := c.LineEnding
+	if lineEnding == "" {
+		lineEnding = DefaultLineEnding
 	}
+	c.truncateLine(line, lineEnding)
+	line.AppendString(lineEnding)
+	return line, duplicates, nil
+}
 
-	if c.LineEnding != "" {
-		line.AppendString(c.LineEnding)
-	} else {
-		line.AppendString(DefaultLineEnding)
+// encodeStacktrace applies any configured StacktraceEncoder, which must
+// produce a string in the console format.
+func (c consoleEncoder) encodeStacktrace(stack string) string {
+	if c.EncodeStacktrace == nil {
+		return stack
+	}
+	arr := getSliceEncoder()
+	defer putSliceEncoder(arr)
+	c.EncodeStacktrace(stack, primitiveArrayEncoder{arr})
+	if len(arr.elems) == 1 {
+		if s, ok := arr.elems[0].(string); ok {
+			return s
+		}
 	}
-	return line, nil
+	return stack
 }
 
-func (c consoleEncoder) writeContext(line *buffer.Buffer, extra []Field) {
+func (c consoleEncoder) writeContext(line *buffer.Buffer, extra []Field) (duplicates []string) {
 	context := c.jsonEncoder.Clone().(*jsonEncoder)
 	defer func() {
 		// putJSONEncoder assumes the buffer is still used, but we write out the buffer so
@@ -144,14 +181,19 @@ func (c consoleEncoder) writeContext(line *buffer.Buffer, extra []Field) {
 
 	addFields(context, extra)
 	context.closeOpenNamespaces()
+	duplicates = context.resolveDuplicateKeys()
+	if context.SortKeys {
+		context.sortFields(0)
+	}
 	if context.buf.Len() == 0 {
-		return
+		return duplicates
 	}
 
 	c.addSeparatorIfNecessary(line)
 	line.AppendByte('{')
 	line.Write(context.buf.Bytes())
 	line.AppendByte('}')
+	return duplicates
 }
 
 func (c consoleEncoder) addSeparatorIfNecessary(line *buffer.Buffer) {
@@ -83,7 +83,7 @@ func (c *ioCore) Check(ent Entry, ce *CheckedEntry) *CheckedEntry {
 }
 
 func (c *ioCore) Write(ent Entry, fields []Field) error {
-	buf, err := c.enc.EncodeEntry(ent, fields)
+	buf, duplicates, err := encodeEntry(c.enc, ent, fields)
 	if err != nil {
 		return err
 	}
@@ -97,6 +97,11 @@ func (c *ioCore) Write(ent Entry, fields []Field) error {
 		// errors, pending a clean solution to issue #370.
 		c.Sync()
 	}
+	if len(duplicates) > 0 {
+		// Report duplicated keys only now that the entry is safely written.
+		c.enc.(duplicateKeyEncoder).reportDuplicateKeys(duplicates)
+		return &DuplicateKeyError{Keys: duplicates}
+	}
 	return nil
 }
 
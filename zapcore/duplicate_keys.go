@@ -0,0 +1,369 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/internal/bufferpool"
+)
+
+// DuplicateKeyPolicy determines how the JSON and console encoders handle a
+// key that appears more than once in the same JSON object. Duplicates can
+// come from the entry's metadata (for example, the message key), from fields
+// accumulated with With, and from fields passed at the log site.
+//
+// The entry's metadata keys are reserved: when a field has the same key as
+// the level, message, or other metadata, KeepLastDuplicateKey and
+// KeepFirstDuplicateKey drop the field, and the other policies rename it.
+//
+// Policies apply to the top level of the encoded entry and to each namespace
+// opened with OpenNamespace. Keys inside objects and arrays added with
+// AddObject, AddArray, and AddReflected are left alone.
+type DuplicateKeyPolicy uint8
+
+const (
+	// AllowDuplicateKeys writes every key as-is. This is the default, and it
+	// has no runtime cost.
+	AllowDuplicateKeys DuplicateKeyPolicy = iota
+	// KeepLastDuplicateKey keeps only the last occurrence of each key, which
+	// matches the behavior of most JSON parsers.
+	KeepLastDuplicateKey
+	// KeepFirstDuplicateKey keeps only the first occurrence of each key.
+	KeepFirstDuplicateKey
+	// RenameDuplicateKey keeps every occurrence, adding a numeric suffix to
+	// all but the first. For example, the second "id" becomes "id_2".
+	RenameDuplicateKey
+	// ReportDuplicateKey renames duplicates like RenameDuplicateKey and also
+	// reports them. Encoders pass each duplicated key to
+	// EncoderConfig.OnDuplicateKey, and Cores return a *DuplicateKeyError from
+	// Write once the entry has been written. zap.Config uses the error to
+	// panic in development and to report an internal error otherwise,
+	// mirroring DPanicLevel.
+	ReportDuplicateKey
+)
+
+// String returns the name used to configure the policy.
+func (p DuplicateKeyPolicy) String() string {
+	switch p {
+	case AllowDuplicateKeys:
+		return "allow"
+	case KeepLastDuplicateKey:
+		return "keepLast"
+	case KeepFirstDuplicateKey:
+		return "keepFirst"
+	case RenameDuplicateKey:
+		return "rename"
+	case ReportDuplicateKey:
+		return "report"
+	default:
+		return fmt.Sprintf("DuplicateKeyPolicy(%d)", p)
+	}
+}
+
+// MarshalText marshals the DuplicateKeyPolicy to text.
+func (p DuplicateKeyPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText unmarshals text to a DuplicateKeyPolicy. "allow" and the
+// empty string are unmarshaled to AllowDuplicateKeys, "keepLast" to
+// KeepLastDuplicateKey, "keepFirst" to KeepFirstDuplicateKey, "rename" to
+// RenameDuplicateKey, and "report" to ReportDuplicateKey.
+func (p *DuplicateKeyPolicy) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "allow", "":
+		*p = AllowDuplicateKeys
+	case "keepLast":
+		*p = KeepLastDuplicateKey
+	case "keepFirst":
+		*p = KeepFirstDuplicateKey
+	case "rename":
+		*p = RenameDuplicateKey
+	case "report":
+		*p = ReportDuplicateKey
+	default:
+		return fmt.Errorf("unrecognized duplicate key policy: %q", text)
+	}
+	return nil
+}
+
+// DuplicateKeyError is returned by a Core's Write method, after the entry has
+// been written, when the ReportDuplicateKey policy found duplicated keys.
+type DuplicateKeyError struct {
+	Keys []string // each duplicated key, once per extra occurrence
+}
+
+func (e *DuplicateKeyError) Error() string {
+	if len(e.Keys) == 1 {
+		return fmt.Sprintf("duplicate key %q in log entry", e.Keys[0])
+	}
+	return fmt.Sprintf("duplicate keys %q in log entry", e.Keys)
+}
+
+// duplicateKeyEncoder is implemented by encoders that can hand the keys found
+// by ReportDuplicateKey back to their caller, so that Cores can report them
+// after the entry is written rather than while it's being encoded.
+type duplicateKeyEncoder interface {
+	encodeEntry(Entry, []Field) (buf *buffer.Buffer, duplicates []string, err error)
+	reportDuplicateKeys(keys []string)
+}
+
+// encodeEntry encodes an entry with enc, returning any duplicated keys that
+// still need to be reported.
+func encodeEntry(enc Encoder, ent Entry, fields []Field) (*buffer.Buffer, []string, error) {
+	if denc, ok := enc.(duplicateKeyEncoder); ok {
+		return denc.encodeEntry(ent, fields)
+	}
+	buf, err := enc.EncodeEntry(ent, fields)
+	return buf, nil, err
+}
+
+// reportDuplicateKeys passes each key to OnDuplicateKey, if it's set.
+func (enc *jsonEncoder) reportDuplicateKeys(keys []string) {
+	if enc.OnDuplicateKey == nil {
+		return
+	}
+	for _, key := range keys {
+		enc.OnDuplicateKey(key)
+	}
+}
+
+// jsonKey records where a key and its value were written to a jsonEncoder's
+// buffer.
+type jsonKey struct {
+	key   string
+	meta  bool // written for the entry's metadata rather than a field
+	scope int  // number of enclosing namespaces
+	start int  // offset of the opening quote
+	colon int  // offset just past the closing quote
+	end   int  // offset just past the value, or -1 if it's still being written
+}
+
+func (enc *jsonEncoder) tracksKeys() bool {
+	return enc.EncoderConfig != nil &&
+		(enc.DuplicateKeys != AllowDuplicateKeys || enc.MaxEntrySize > 0) &&
+		enc.nesting == 0
+}
+
+// closeKeys marks the values of all keys at or below the given scope as
+// complete.
+func (enc *jsonEncoder) closeKeys(scope int) {
+	for i := len(enc.keys) - 1; i >= 0 && enc.keys[i].scope >= scope; i-- {
+		if enc.keys[i].end < 0 {
+			enc.keys[i].end = enc.buf.Len()
+		}
+	}
+}
+
+// appendKeys adds keys recorded by another encoder whose buffer was copied
+// into ours at the given offset.
+func (enc *jsonEncoder) appendKeys(keys []jsonKey, offset int) {
+	for _, k := range keys {
+		k.start += offset
+		k.colon += offset
+		if k.end >= 0 {
+			k.end += offset
+		}
+		enc.keys = append(enc.keys, k)
+	}
+}
+
+// resolveDuplicateKeys applies the configured DuplicateKeyPolicy to the keys
+// recorded so far, returning the keys that ReportDuplicateKey should report.
+// It must be called after all namespaces are closed.
+func (enc *jsonEncoder) resolveDuplicateKeys() (reported []string) {
+	enc.closeKeys(0)
+	if enc.DuplicateKeys == AllowDuplicateKeys || len(enc.keys) < 2 {
+		return nil
+	}
+
+	type edit struct {
+		drop   bool
+		suffix int
+	}
+	type scopedKey struct {
+		key   string
+		scope int
+	}
+	var (
+		edits map[int]edit
+		seen  = make(map[scopedKey]int, len(enc.keys))
+		taken map[scopedKey]struct{} // for picking unique names when renaming
+	)
+	rename := func(i int) {
+		k := enc.keys[i]
+		if taken == nil {
+			taken = make(map[scopedKey]struct{}, len(enc.keys))
+			for _, k := range enc.keys {
+				taken[scopedKey{k.key, k.scope}] = struct{}{}
+			}
+		}
+		n := 2
+		for {
+			renamed := scopedKey{k.key + "_" + strconv.Itoa(n), k.scope}
+			if _, ok := taken[renamed]; !ok {
+				taken[renamed] = struct{}{}
+				break
+			}
+			n++
+		}
+		edits[i] = edit{suffix: n}
+		if enc.DuplicateKeys == ReportDuplicateKey {
+			reported = append(reported, k.key)
+		}
+	}
+	for i, k := range enc.keys {
+		id := scopedKey{k.key, k.scope}
+		first, ok := seen[id]
+		if !ok {
+			seen[id] = i
+			continue
+		}
+		if edits == nil {
+			edits = make(map[int]edit)
+		}
+		keep := enc.DuplicateKeys == KeepLastDuplicateKey || enc.DuplicateKeys == KeepFirstDuplicateKey
+		switch {
+		case enc.keys[first].meta && k.meta:
+			// Configured by the user; leave it alone.
+		case enc.keys[first].meta || k.meta:
+			// Metadata is reserved, so the field gives way.
+			field := i
+			if k.meta {
+				field = first
+				seen[id] = i
+			}
+			if keep {
+				edits[field] = edit{drop: true}
+			} else {
+				rename(field)
+			}
+		case enc.DuplicateKeys == KeepLastDuplicateKey:
+			edits[first] = edit{drop: true}
+			seen[id] = i
+		case enc.DuplicateKeys == KeepFirstDuplicateKey:
+			edits[i] = edit{drop: true}
+		default:
+			rename(i)
+		}
+	}
+	if len(edits) == 0 {
+		return nil
+	}
+
+	// Rewrite the buffer, keeping track of how offsets shift so that the
+	// remaining keys stay accurate.
+	type anchor struct{ old, new int }
+	var (
+		src     = enc.buf.Bytes()
+		out     = bufferpool.Get()
+		cursor  = 0
+		anchors = []anchor{{0, 0}}
+		dropped = make([]bool, len(enc.keys))
+	)
+	for i, k := range enc.keys {
+		if k.start < cursor {
+			// Nested in a namespace that was already dropped.
+			dropped[i] = true
+			continue
+		}
+		e, ok := edits[i]
+		if !ok {
+			continue
+		}
+		if e.suffix > 0 {
+			out.Write(src[cursor : k.colon-1])
+			out.AppendByte('_')
+			out.AppendInt(int64(e.suffix))
+			cursor = k.colon - 1
+			anchors = append(anchors, anchor{cursor, out.Len()})
+			continue
+		}
+
+		// Drop the key and value along with one adjacent separator: the
+		// preceding one if it exists, otherwise the following one.
+		dropped[i] = true
+		prefix := src[cursor:k.start]
+		if sep := separatorSuffix(prefix); sep > 0 {
+			out.Write(prefix[:len(prefix)-sep])
+			cursor = k.end
+		} else {
+			out.Write(prefix)
+			cursor = k.end + separatorPrefix(src[k.end:])
+		}
+		anchors = append(anchors, anchor{cursor, out.Len()})
+	}
+	out.Write(src[cursor:])
+
+	remap := func(offset int) int {
+		i := sort.Search(len(anchors), func(i int) bool { return anchors[i].old > offset }) - 1
+		return anchors[i].new + offset - anchors[i].old
+	}
+	keys := enc.keys[:0]
+	for i, k := range enc.keys {
+		if dropped[i] {
+			continue
+		}
+		if e := edits[i]; e.suffix > 0 {
+			k.key += "_" + strconv.Itoa(e.suffix)
+		}
+		k.start, k.colon, k.end = remap(k.start), remap(k.colon), remap(k.end)
+		keys = append(keys, k)
+	}
+	enc.keys = keys
+	enc.buf.Free()
+	enc.buf = out
+	return reported
+}
+
+var (
+	_comma       = []byte{','}
+	_commaSpaced = []byte{',', ' '}
+)
+
+// separatorSuffix returns the length of the element separator at the end of
+// bs, if any.
+func separatorSuffix(bs []byte) int {
+	switch {
+	case bytes.HasSuffix(bs, _commaSpaced):
+		return len(_commaSpaced)
+	case bytes.HasSuffix(bs, _comma):
+		return len(_comma)
+	}
+	return 0
+}
+
+// separatorPrefix returns the length of the element separator at the start
+// of bs, if any.
+func separatorPrefix(bs []byte) int {
+	switch {
+	case bytes.HasPrefix(bs, _commaSpaced):
+		return len(_commaSpaced)
+	case bytes.HasPrefix(bs, _comma):
+		return len(_comma)
+	}
+	return 0
+}
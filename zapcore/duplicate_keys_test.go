@@ -0,0 +1,247 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/internal/ztest"
+	. "go.uber.org/zap/zapcore"
+)
+
+func TestDuplicateKeyPolicyText(t *testing.T) {
+	for _, p := range []DuplicateKeyPolicy{
+		AllowDuplicateKeys,
+		KeepLastDuplicateKey,
+		KeepFirstDuplicateKey,
+		RenameDuplicateKey,
+		ReportDuplicateKey,
+	} {
+		text, err := p.MarshalText()
+		require.NoError(t, err, "Unexpected error marshaling %v.", p)
+
+		var unmarshaled DuplicateKeyPolicy
+		require.NoError(t, unmarshaled.UnmarshalText(text), "Unexpected error unmarshaling %q.", text)
+		assert.Equal(t, p, unmarshaled, "Unexpected policy after round-trip.")
+	}
+
+	var p DuplicateKeyPolicy
+	assert.NoError(t, p.UnmarshalText(nil), "Expected empty text to be valid.")
+	assert.Equal(t, AllowDuplicateKeys, p, "Expected empty text to allow duplicates.")
+	assert.Error(t, p.UnmarshalText([]byte("merge")), "Expected error unmarshaling unknown policy.")
+	assert.Equal(t, "DuplicateKeyPolicy(42)", DuplicateKeyPolicy(42).String(), "Unexpected string for unknown policy.")
+}
+
+func TestDuplicateKeyPolicies(t *testing.T) {
+	ent := Entry{Level: InfoLevel, Message: "hello"}
+	context := []Field{zap.String("id", "ctx"), zap.Int("n", 1)}
+
+	tests := []struct {
+		desc            string
+		policy          DuplicateKeyPolicy
+		context         []Field
+		fields          []Field
+		expectedJSON    string
+		expectedConsole string
+	}{
+		{
+			desc:            "allow",
+			policy:          AllowDuplicateKeys,
+			context:         context,
+			fields:          []Field{zap.String("id", "call")},
+			expectedJSON:    `{"L":"info","M":"hello","id":"ctx","n":1,"id":"call"}`,
+			expectedConsole: `info	hello	{"id": "ctx", "n": 1, "id": "call"}`,
+		},
+		{
+			desc:            "keep last",
+			policy:          KeepLastDuplicateKey,
+			context:         context,
+			fields:          []Field{zap.String("id", "call")},
+			expectedJSON:    `{"L":"info","M":"hello","n":1,"id":"call"}`,
+			expectedConsole: `info	hello	{"n": 1, "id": "call"}`,
+		},
+		{
+			desc:            "keep first",
+			policy:          KeepFirstDuplicateKey,
+			context:         context,
+			fields:          []Field{zap.String("id", "call")},
+			expectedJSON:    `{"L":"info","M":"hello","id":"ctx","n":1}`,
+			expectedConsole: `info	hello	{"id": "ctx", "n": 1}`,
+		},
+		{
+			desc:            "rename",
+			policy:          RenameDuplicateKey,
+			context:         context,
+			fields:          []Field{zap.String("id", "call"), zap.String("id", "again"), zap.String("id_3", "taken")},
+			expectedJSON:    `{"L":"info","M":"hello","id":"ctx","n":1,"id_2":"call","id_4":"again","id_3":"taken"}`,
+			expectedConsole: `info	hello	{"id": "ctx", "n": 1, "id_2": "call", "id_4": "again", "id_3": "taken"}`,
+		},
+		{
+			desc:            "keep last keeps metadata",
+			policy:          KeepLastDuplicateKey,
+			fields:          []Field{zap.String("M", "field"), zap.String("L", "field")},
+			expectedJSON:    `{"L":"info","M":"hello"}`,
+			expectedConsole: `info	hello	{"M": "field", "L": "field"}`,
+		},
+		{
+			desc:            "keep first keeps metadata",
+			policy:          KeepFirstDuplicateKey,
+			fields:          []Field{zap.String("M", "field")},
+			expectedJSON:    `{"L":"info","M":"hello"}`,
+			expectedConsole: `info	hello	{"M": "field"}`,
+		},
+		{
+			desc:            "rename keeps metadata",
+			policy:          RenameDuplicateKey,
+			fields:          []Field{zap.String("M", "field")},
+			expectedJSON:    `{"L":"info","M":"hello","M_2":"field"}`,
+			expectedConsole: `info	hello	{"M": "field"}`,
+		},
+		{
+			desc:            "keep first drops leading duplicates in context",
+			policy:          KeepFirstDuplicateKey,
+			context:         []Field{zap.Int("n", 1), zap.Int("n", 2), zap.Int("n", 3)},
+			expectedJSON:    `{"L":"info","M":"hello","n":1}`,
+			expectedConsole: `info	hello	{"n": 1}`,
+		},
+		{
+			desc:            "keep last drops leading duplicates in context",
+			policy:          KeepLastDuplicateKey,
+			context:         []Field{zap.Int("n", 1), zap.Int("n", 2), zap.Int("n", 3)},
+			expectedJSON:    `{"L":"info","M":"hello","n":3}`,
+			expectedConsole: `info	hello	{"n": 3}`,
+		},
+		{
+			desc:            "namespaces are separate scopes",
+			policy:          KeepLastDuplicateKey,
+			context:         []Field{zap.Int("n", 1), zap.Namespace("ns"), zap.Int("n", 2)},
+			fields:          []Field{zap.Int("n", 3)},
+			expectedJSON:    `{"L":"info","M":"hello","n":1,"ns":{"n":3}}`,
+			expectedConsole: `info	hello	{"n": 1, "ns": {"n": 3}}`,
+		},
+		{
+			desc:            "duplicate namespace",
+			policy:          KeepLastDuplicateKey,
+			context:         []Field{zap.Int("ns", 1), zap.Namespace("ns"), zap.Int("n", 2)},
+			expectedJSON:    `{"L":"info","M":"hello","ns":{"n":2}}`,
+			expectedConsole: `info	hello	{"ns": {"n": 2}}`,
+		},
+		{
+			desc:    "nested objects are ignored",
+			policy:  KeepLastDuplicateKey,
+			context: []Field{zap.Int("n", 1)},
+			fields: []Field{zap.Object("obj", ObjectMarshalerFunc(func(enc ObjectEncoder) error {
+				enc.AddInt("n", 2)
+				enc.AddInt("n", 3)
+				return nil
+			}))},
+			expectedJSON:    `{"L":"info","M":"hello","n":1,"obj":{"n":2,"n":3}}`,
+			expectedConsole: `info	hello	{"n": 1, "obj": {"n": 2, "n": 3}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			cfg := EncoderConfig{
+				LevelKey:      "L",
+				MessageKey:    "M",
+				EncodeLevel:   LowercaseLevelEncoder,
+				DuplicateKeys: tt.policy,
+			}
+
+			encoders := []struct {
+				enc      Encoder
+				expected string
+			}{
+				{NewJSONEncoder(cfg), tt.expectedJSON},
+				{NewConsoleEncoder(cfg), tt.expectedConsole},
+			}
+			for _, e := range encoders {
+				for _, f := range tt.context {
+					f.AddTo(e.enc)
+				}
+				buf, err := e.enc.EncodeEntry(ent, tt.fields)
+				require.NoError(t, err, "Unexpected error encoding entry.")
+				assert.Equal(t, e.expected+"\n", buf.String(), "Unexpected output.")
+				buf.Free()
+			}
+		})
+	}
+}
+
+func TestDuplicateKeysAfterFields(t *testing.T) {
+	// The stacktrace is written after the fields, but a field with its key
+	// still gives way.
+	ent := Entry{Message: "hello", Stack: "trace"}
+	fields := []Field{zap.String("S", "field")}
+	for _, tt := range []struct {
+		policy   DuplicateKeyPolicy
+		expected string
+	}{
+		{KeepFirstDuplicateKey, `{"M":"hello","S":"trace"}`},
+		{KeepLastDuplicateKey, `{"M":"hello","S":"trace"}`},
+		{RenameDuplicateKey, `{"M":"hello","S_2":"field","S":"trace"}`},
+	} {
+		enc := NewJSONEncoder(EncoderConfig{MessageKey: "M", StacktraceKey: "S", DuplicateKeys: tt.policy})
+		buf, err := enc.EncodeEntry(ent, fields)
+		require.NoError(t, err, "Unexpected error encoding entry.")
+		assert.Equal(t, tt.expected+"\n", buf.String(), "Unexpected output with policy %v.", tt.policy)
+		buf.Free()
+	}
+}
+
+func TestReportDuplicateKey(t *testing.T) {
+	var reported []string
+	enc := NewJSONEncoder(EncoderConfig{
+		MessageKey:     "M",
+		DuplicateKeys:  ReportDuplicateKey,
+		OnDuplicateKey: func(key string) { reported = append(reported, key) },
+	})
+	enc.AddString("id", "ctx")
+
+	buf, err := enc.EncodeEntry(Entry{Message: "hello"}, []Field{zap.String("id", "call")})
+	require.NoError(t, err, "Unexpected error encoding entry.")
+	assert.Equal(t, `{"M":"hello","id":"ctx","id_2":"call"}`+"\n", buf.String(), "Unexpected output.")
+	assert.Equal(t, []string{"id"}, reported, "Unexpected reported keys.")
+}
+
+func TestCoreReportsDuplicateKeysAfterWrite(t *testing.T) {
+	sink := &ztest.Buffer{}
+	var written []string
+	core := NewCore(NewJSONEncoder(EncoderConfig{
+		MessageKey:    "M",
+		DuplicateKeys: ReportDuplicateKey,
+		OnDuplicateKey: func(string) {
+			written = sink.Lines()
+		},
+	}), sink, DebugLevel)
+
+	err := core.With([]Field{zap.String("id", "ctx")}).Write(Entry{Message: "hello"}, []Field{zap.String("id", "call")})
+	assert.Equal(t, &DuplicateKeyError{Keys: []string{"id"}}, err, "Unexpected error writing entry.")
+	assert.Equal(t, `duplicate key "id" in log entry`, err.Error(), "Unexpected error message.")
+	assert.Equal(t, []string{`{"M":"hello","id":"ctx","id_2":"call"}`}, written, "Expected entry to be written before reporting.")
+
+	assert.NoError(t, core.Write(Entry{Message: "hello"}, []Field{zap.String("id", "call")}), "Unexpected error without duplicates.")
+}
@@ -22,6 +22,7 @@ package zapcore
 
 import (
 	"encoding/json"
+	"io"
 	"time"
 
 	"go.uber.org/zap/buffer"
@@ -46,7 +47,14 @@ func LowercaseLevelEncoder(l Level, enc PrimitiveArrayEncoder) {
 
 // LowercaseColorLevelEncoder serializes a Level to a lowercase string and adds coloring.
 // For example, InfoLevel is serialized to "info" and colored blue.
+//
+// If the encoder's ColorMode disables color, it behaves like
+// LowercaseLevelEncoder.
 func LowercaseColorLevelEncoder(l Level, enc PrimitiveArrayEncoder) {
+	if colorDisabled(enc) {
+		LowercaseLevelEncoder(l, enc)
+		return
+	}
 	s, ok := _levelToLowercaseColorString[l]
 	if !ok {
 		s = _unknownLevelColor.Add(l.String())
@@ -62,7 +70,14 @@ func CapitalLevelEncoder(l Level, enc PrimitiveArrayEncoder) {
 
 // CapitalColorLevelEncoder serializes a Level to an all-caps string and adds color.
 // For example, InfoLevel is serialized to "INFO" and colored blue.
+//
+// If the encoder's ColorMode disables color, it behaves like
+// CapitalLevelEncoder.
 func CapitalColorLevelEncoder(l Level, enc PrimitiveArrayEncoder) {
+	if colorDisabled(enc) {
+		CapitalLevelEncoder(l, enc)
+		return
+	}
 	s, ok := _levelToCapitalColorString[l]
 	if !ok {
 		s = _unknownLevelColor.Add(l.CapitalString())
@@ -73,21 +88,55 @@ func CapitalColorLevelEncoder(l Level, enc PrimitiveArrayEncoder) {
 // UnmarshalText unmarshals text to a LevelEncoder. "capital" is unmarshaled to
 // CapitalLevelEncoder, "coloredCapital" is unmarshaled to CapitalColorLevelEncoder,
 // "colored" is unmarshaled to LowercaseColorLevelEncoder, and anything else
-// is unmarshaled to LowercaseLevelEncoder.
+// is unmarshaled to LowercaseLevelEncoder. Names added with
+// RegisterLevelEncoder are unmarshaled to the registered encoder.
 func (e *LevelEncoder) UnmarshalText(text []byte) error {
-	switch string(text) {
-	case "capital":
-		*e = CapitalLevelEncoder
-	case "capitalColor":
-		*e = CapitalColorLevelEncoder
-	case "color":
-		*e = LowercaseColorLevelEncoder
-	default:
+	if enc, ok := LookupLevelEncoder(string(text)); ok {
+		*e = enc
+	} else {
 		*e = LowercaseLevelEncoder
 	}
 	return nil
 }
 
+// UnmarshalYAML unmarshals YAML to a LevelEncoder.
+// If value is an object with a "mapping" field, it's unmarshaled to a
+// LevelEncoder which serializes levels to the mapped strings or integers,
+// like LevelEncoderOfNames or LevelEncoderOfNumbers. A zap.Config with a
+// mapping accepts the mapped values for its own level; to parse them
+// anywhere else, pass the encoder to RegisterLevelEncoderAliases.
+//     levelEncoder:
+//       mapping: {warn: WARNING, fatal: critical}
+// If value is string, it uses UnmarshalText.
+//     levelEncoder: capital
+func (e *LevelEncoder) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var o struct {
+		Mapping map[string]interface{} `json:"mapping" yaml:"mapping"`
+	}
+	if err := unmarshal(&o); err == nil {
+		enc, err := unmarshalLevelMapping(o.Mapping)
+		if err != nil {
+			return err
+		}
+		*e = enc
+		return nil
+	}
+
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return e.UnmarshalText([]byte(s))
+}
+
+// UnmarshalJSON unmarshals JSON to a LevelEncoder as same way UnmarshalYAML
+// does.
+func (e *LevelEncoder) UnmarshalJSON(data []byte) error {
+	return e.UnmarshalYAML(func(v interface{}) error {
+		return json.Unmarshal(data, v)
+	})
+}
+
 // A TimeEncoder serializes a time.Time to a primitive type.
 type TimeEncoder func(time.Time, PrimitiveArrayEncoder)
 
@@ -160,26 +209,26 @@ func TimeEncoderOfLayout(layout string) TimeEncoder {
 	}
 }
 
+// TimeEncoderInLocation returns a TimeEncoder which converts a time.Time to
+// the given location before serializing it with e.
+func TimeEncoderInLocation(e TimeEncoder, loc *time.Location) TimeEncoder {
+	return func(t time.Time, enc PrimitiveArrayEncoder) {
+		e(t.In(loc), enc)
+	}
+}
+
 // UnmarshalText unmarshals text to a TimeEncoder.
 // "rfc3339nano" and "RFC3339Nano" are unmarshaled to RFC3339NanoTimeEncoder.
 // "rfc3339" and "RFC3339" are unmarshaled to RFC3339TimeEncoder.
 // "iso8601" and "ISO8601" are unmarshaled to ISO8601TimeEncoder.
 // "millis" is unmarshaled to EpochMillisTimeEncoder.
 // "nanos" is unmarshaled to EpochNanosEncoder.
+// Names added with RegisterTimeEncoder are unmarshaled to the registered encoder.
 // Anything else is unmarshaled to EpochTimeEncoder.
 func (e *TimeEncoder) UnmarshalText(text []byte) error {
-	switch string(text) {
-	case "rfc3339nano", "RFC3339Nano":
-		*e = RFC3339NanoTimeEncoder
-	case "rfc3339", "RFC3339":
-		*e = RFC3339TimeEncoder
-	case "iso8601", "ISO8601":
-		*e = ISO8601TimeEncoder
-	case "millis":
-		*e = EpochMillisTimeEncoder
-	case "nanos":
-		*e = EpochNanosTimeEncoder
-	default:
+	if enc, ok := LookupTimeEncoder(string(text)); ok {
+		*e = enc
+	} else {
 		*e = EpochTimeEncoder
 	}
 	return nil
@@ -189,14 +238,35 @@ func (e *TimeEncoder) UnmarshalText(text []byte) error {
 // If value is an object with a "layout" field, it will be unmarshaled to  TimeEncoder with given layout.
 //     timeEncoder:
 //       layout: 06/01/02 03:04pm
+// If value is an object with a "name" field, the name is unmarshaled with
+// UnmarshalText. Either form may also have a "timeZone" field, naming a time
+// zone as TimeZone does, to convert times to before serializing them.
+//     timeEncoder:
+//       name: iso8601
+//       timeZone: America/New_York
 // If value is string, it uses UnmarshalText.
 //     timeEncoder: iso8601
 func (e *TimeEncoder) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var o struct {
-		Layout string `json:"layout" yaml:"layout"`
+		Layout   string `json:"layout" yaml:"layout"`
+		Name     string `json:"name" yaml:"name"`
+		TimeZone string `json:"timeZone" yaml:"timeZone"`
 	}
 	if err := unmarshal(&o); err == nil {
-		*e = TimeEncoderOfLayout(o.Layout)
+		var tz TimeZone
+		if err := tz.UnmarshalText([]byte(o.TimeZone)); err != nil {
+			return err
+		}
+		if o.Name != "" {
+			if err := e.UnmarshalText([]byte(o.Name)); err != nil {
+				return err
+			}
+		} else {
+			*e = TimeEncoderOfLayout(o.Layout)
+		}
+		if loc := tz.Location(); loc != nil {
+			*e = TimeEncoderInLocation(*e, loc)
+		}
 		return nil
 	}
 
@@ -242,16 +312,12 @@ func StringDurationEncoder(d time.Duration, enc PrimitiveArrayEncoder) {
 
 // UnmarshalText unmarshals text to a DurationEncoder. "string" is unmarshaled
 // to StringDurationEncoder, and anything else is unmarshaled to
-// NanosDurationEncoder.
+// NanosDurationEncoder. Names added with RegisterDurationEncoder are
+// unmarshaled to the registered encoder.
 func (e *DurationEncoder) UnmarshalText(text []byte) error {
-	switch string(text) {
-	case "string":
-		*e = StringDurationEncoder
-	case "nanos":
-		*e = NanosDurationEncoder
-	case "ms":
-		*e = MillisDurationEncoder
-	default:
+	if enc, ok := LookupDurationEncoder(string(text)); ok {
+		*e = enc
+	} else {
 		*e = SecondsDurationEncoder
 	}
 	return nil
@@ -275,12 +341,16 @@ func ShortCallerEncoder(caller EntryCaller, enc PrimitiveArrayEncoder) {
 }
 
 // UnmarshalText unmarshals text to a CallerEncoder. "full" is unmarshaled to
-// FullCallerEncoder and anything else is unmarshaled to ShortCallerEncoder.
+// FullCallerEncoder, "module" to ModuleCallerEncoder, "function" to
+// FunctionCallerEncoder, "object" to ObjectCallerEncoder, and "trailing:N" to
+// TrailingCallerEncoder(N).
+// Anything else is unmarshaled to ShortCallerEncoder.
+// Names added with RegisterCallerEncoder are unmarshaled to the registered
+// encoder.
 func (e *CallerEncoder) UnmarshalText(text []byte) error {
-	switch string(text) {
-	case "full":
-		*e = FullCallerEncoder
-	default:
+	if enc, ok := LookupCallerEncoder(string(text)); ok {
+		*e = enc
+	} else {
 		*e = ShortCallerEncoder
 	}
 	return nil
@@ -295,13 +365,13 @@ func FullNameEncoder(loggerName string, enc PrimitiveArrayEncoder) {
 	enc.AppendString(loggerName)
 }
 
-// UnmarshalText unmarshals text to a NameEncoder. Currently, everything is
-// unmarshaled to FullNameEncoder.
+// UnmarshalText unmarshals text to a NameEncoder. Names added with
+// RegisterNameEncoder are unmarshaled to the registered encoder, and anything
+// else is unmarshaled to FullNameEncoder.
 func (e *NameEncoder) UnmarshalText(text []byte) error {
-	switch string(text) {
-	case "full":
-		*e = FullNameEncoder
-	default:
+	if enc, ok := LookupNameEncoder(string(text)); ok {
+		*e = enc
+	} else {
 		*e = FullNameEncoder
 	}
 	return nil
@@ -330,9 +400,60 @@ type EncoderConfig struct {
 	// Unlike the other primitive type encoders, EncodeName is optional. The
 	// zero value falls back to FullNameEncoder.
 	EncodeName NameEncoder `json:"nameEncoder" yaml:"nameEncoder"`
+	// Unlike the other primitive type encoders, EncodeStacktrace is
+	// optional. The zero value writes the stacktrace as a single string. The
+	// console encoder always writes a string, but respects any filtering.
+	EncodeStacktrace StacktraceEncoder `json:"stacktraceEncoder" yaml:"stacktraceEncoder"`
+	// Unlike the other primitive type encoders, EncodeFieldTime is optional.
+	// It serializes time.Times added as fields, like with zap.Time, leaving
+	// EncodeTime for the entry's timestamp. The zero value falls back to
+	// EncodeTime.
+	EncodeFieldTime TimeEncoder `json:"fieldTimeEncoder" yaml:"fieldTimeEncoder"`
+	// Configure the encoder for interface{} type objects, such as those
+	// added with zap.Reflect or zap.Any. If not provided, objects are
+	// encoded using json.Encoder with HTML escaping disabled.
+	NewReflectedEncoder func(io.Writer) ReflectedEncoder `json:"-" yaml:"-"`
 	// Configures the field separator used by the console encoder. Defaults
 	// to tab.
 	ConsoleSeparator string `json:"consoleSeparator" yaml:"consoleSeparator"`
+	// ColorMode controls whether the color level encoders add color. The
+	// zero value, ColorAlways, preserves their historical behavior. See
+	// ColorMode for details.
+	ColorMode ColorMode `json:"colorMode" yaml:"colorMode"`
+	// DuplicateKeys controls how keys that appear more than once in the same
+	// object are handled. The zero value, AllowDuplicateKeys, writes them
+	// as-is. See DuplicateKeyPolicy for details.
+	DuplicateKeys DuplicateKeyPolicy `json:"duplicateKeys" yaml:"duplicateKeys"`
+	// OnDuplicateKey is called with each duplicated key found under the
+	// ReportDuplicateKey policy. Cores call it once the entry has been
+	// written. It's optional.
+	OnDuplicateKey func(key string) `json:"-" yaml:"-"`
+	// Limits on the size of encoded output. Values past a limit are cut
+	// short and marked with a note like "…(truncated 1.2MB)" rather than
+	// dropped. Zero disables a limit.
+	//
+	// MaxStringLength caps the bytes kept from each string value, including
+	// the message and stacktrace.
+	MaxStringLength int `json:"maxStringLength" yaml:"maxStringLength"`
+	// MaxArrayLength caps the elements kept from each array.
+	MaxArrayLength int `json:"maxArrayLength" yaml:"maxArrayLength"`
+	// MaxDepth caps how deeply arrays and objects may nest within a field.
+	MaxDepth int `json:"maxDepth" yaml:"maxDepth"`
+	// MaxEntrySize caps the size in bytes of each encoded entry, including
+	// the line ending. The JSON encoder drops trailing fields to fit, and
+	// the console encoder cuts the line short. The truncation marker is left
+	// out if it doesn't fit. Entries are never smaller than their line
+	// ending, plus "{}" for JSON, so smaller limits are exceeded by that
+	// much.
+	MaxEntrySize int `json:"maxEntrySize" yaml:"maxEntrySize"`
+	// TimeZone is the time zone that entry timestamps are converted to
+	// before EncodeTime serializes them. The zero value leaves them as-is.
+	TimeZone TimeZone `json:"timeZone" yaml:"timeZone"`
+	// SortKeys makes output independent of the order in which fields were
+	// added: the entry's metadata comes first, as usual, followed by its
+	// fields sorted by key. Objects nested within fields are sorted too.
+	// The stacktrace, if any, still comes last.
+	SortKeys bool `json:"sortKeys" yaml:"sortKeys"`
 }
 
 // ObjectEncoder is a strongly-typed, encoding-agnostic interface for adding a
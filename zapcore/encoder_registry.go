@@ -0,0 +1,212 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var errNoEncoderName = errors.New("no encoder name specified")
+
+// encoderRegistry maps names to one kind of primitive type encoder, so that
+// the encoders' UnmarshalText methods can find them.
+type encoderRegistry struct {
+	kind string
+
+	mu       sync.RWMutex
+	encoders map[string]interface{}
+}
+
+func newEncoderRegistry(kind string, builtin map[string]interface{}) *encoderRegistry {
+	return &encoderRegistry{kind: kind, encoders: builtin}
+}
+
+func (r *encoderRegistry) register(name string, enc interface{}, isNil bool) error {
+	if name == "" {
+		return errNoEncoderName
+	}
+	if isNil {
+		return fmt.Errorf("nil %s encoder for name %q", r.kind, name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.encoders[name]; ok {
+		return fmt.Errorf("%s encoder already registered for name %q", r.kind, name)
+	}
+	r.encoders[name] = enc
+	return nil
+}
+
+func (r *encoderRegistry) lookup(name string) (interface{}, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	enc, ok := r.encoders[name]
+	return enc, ok
+}
+
+var (
+	_levelEncoders = newEncoderRegistry("level", map[string]interface{}{
+		"capital":      LevelEncoder(CapitalLevelEncoder),
+		"capitalColor": LevelEncoder(CapitalColorLevelEncoder),
+		"color":        LevelEncoder(LowercaseColorLevelEncoder),
+		"lowercase":    LevelEncoder(LowercaseLevelEncoder),
+	})
+	_timeEncoders = newEncoderRegistry("time", map[string]interface{}{
+		"rfc3339nano": TimeEncoder(RFC3339NanoTimeEncoder),
+		"RFC3339Nano": TimeEncoder(RFC3339NanoTimeEncoder),
+		"rfc3339":     TimeEncoder(RFC3339TimeEncoder),
+		"RFC3339":     TimeEncoder(RFC3339TimeEncoder),
+		"iso8601":     TimeEncoder(ISO8601TimeEncoder),
+		"ISO8601":     TimeEncoder(ISO8601TimeEncoder),
+		"millis":      TimeEncoder(EpochMillisTimeEncoder),
+		"nanos":       TimeEncoder(EpochNanosTimeEncoder),
+		"epoch":       TimeEncoder(EpochTimeEncoder),
+	})
+	_durationEncoders = newEncoderRegistry("duration", map[string]interface{}{
+		"string":  DurationEncoder(StringDurationEncoder),
+		"nanos":   DurationEncoder(NanosDurationEncoder),
+		"ms":      DurationEncoder(MillisDurationEncoder),
+		"seconds": DurationEncoder(SecondsDurationEncoder),
+	})
+	_callerEncoders = newEncoderRegistry("caller", map[string]interface{}{
+		"full":     CallerEncoder(FullCallerEncoder),
+		"short":    CallerEncoder(ShortCallerEncoder),
+		"module":   CallerEncoder(ModuleCallerEncoder),
+		"function": CallerEncoder(FunctionCallerEncoder),
+		"object":   CallerEncoder(ObjectCallerEncoder),
+	})
+	_nameEncoders = newEncoderRegistry("name", map[string]interface{}{
+		"full": NameEncoder(FullNameEncoder),
+	})
+)
+
+// RegisterLevelEncoder registers a LevelEncoder, which EncoderConfigs
+// unmarshaled from JSON or YAML can then reference by name. The names of
+// Zap's own level encoders are already registered; see
+// LevelEncoder.UnmarshalText.
+//
+// Attempting to register an encoder whose name is already taken returns an
+// error.
+func RegisterLevelEncoder(name string, e LevelEncoder) error {
+	return _levelEncoders.register(name, e, e == nil)
+}
+
+// RegisterTimeEncoder registers a TimeEncoder, which EncoderConfigs
+// unmarshaled from JSON or YAML can then reference by name. The names of
+// Zap's own time encoders are already registered; see
+// TimeEncoder.UnmarshalText.
+//
+// Attempting to register an encoder whose name is already taken returns an
+// error.
+func RegisterTimeEncoder(name string, e TimeEncoder) error {
+	return _timeEncoders.register(name, e, e == nil)
+}
+
+// RegisterDurationEncoder registers a DurationEncoder, which EncoderConfigs
+// unmarshaled from JSON or YAML can then reference by name. The names of
+// Zap's own duration encoders are already registered; see
+// DurationEncoder.UnmarshalText.
+//
+// Attempting to register an encoder whose name is already taken returns an
+// error.
+func RegisterDurationEncoder(name string, e DurationEncoder) error {
+	return _durationEncoders.register(name, e, e == nil)
+}
+
+// RegisterCallerEncoder registers a CallerEncoder, which EncoderConfigs
+// unmarshaled from JSON or YAML can then reference by name. The names of
+// Zap's own caller encoders are already registered; see
+// CallerEncoder.UnmarshalText.
+//
+// Attempting to register an encoder whose name is already taken returns an
+// error.
+func RegisterCallerEncoder(name string, e CallerEncoder) error {
+	return _callerEncoders.register(name, e, e == nil)
+}
+
+// RegisterNameEncoder registers a NameEncoder, which EncoderConfigs
+// unmarshaled from JSON or YAML can then reference by name. The names of
+// Zap's own name encoders are already registered; see
+// NameEncoder.UnmarshalText.
+//
+// Attempting to register an encoder whose name is already taken returns an
+// error.
+func RegisterNameEncoder(name string, e NameEncoder) error {
+	return _nameEncoders.register(name, e, e == nil)
+}
+
+// LookupLevelEncoder returns the LevelEncoder that LevelEncoder.UnmarshalText
+// unmarshals name to, if name is known. UnmarshalText falls back to a default
+// encoder for unknown names.
+func LookupLevelEncoder(name string) (LevelEncoder, bool) {
+	enc, ok := _levelEncoders.lookup(name)
+	if !ok {
+		return nil, false
+	}
+	return enc.(LevelEncoder), true
+}
+
+// LookupTimeEncoder returns the TimeEncoder that TimeEncoder.UnmarshalText
+// unmarshals name to, if name is known. UnmarshalText falls back to a default
+// encoder for unknown names.
+func LookupTimeEncoder(name string) (TimeEncoder, bool) {
+	enc, ok := _timeEncoders.lookup(name)
+	if !ok {
+		return nil, false
+	}
+	return enc.(TimeEncoder), true
+}
+
+// LookupDurationEncoder returns the DurationEncoder that
+// DurationEncoder.UnmarshalText unmarshals name to, if name is known.
+// UnmarshalText falls back to a default encoder for unknown names.
+func LookupDurationEncoder(name string) (DurationEncoder, bool) {
+	enc, ok := _durationEncoders.lookup(name)
+	if !ok {
+		return nil, false
+	}
+	return enc.(DurationEncoder), true
+}
+
+// LookupCallerEncoder returns the CallerEncoder that
+// CallerEncoder.UnmarshalText unmarshals name to, if name is known, including
+// "trailing:N" names. UnmarshalText falls back to a default encoder for
+// unknown names.
+func LookupCallerEncoder(name string) (CallerEncoder, bool) {
+	if enc, ok := _callerEncoders.lookup(name); ok {
+		return enc.(CallerEncoder), true
+	}
+	return unmarshalTrailingCallerEncoder(name)
+}
+
+// LookupNameEncoder returns the NameEncoder that NameEncoder.UnmarshalText
+// unmarshals name to, if name is known. UnmarshalText falls back to a default
+// encoder for unknown names.
+func LookupNameEncoder(name string) (NameEncoder, bool) {
+	enc, ok := _nameEncoders.lookup(name)
+	if !ok {
+		return nil, false
+	}
+	return enc.(NameEncoder), true
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	. "go.uber.org/zap/zapcore"
+)
+
+// _registeredNames makes registered names unique across repeated runs of
+// the tests in one process, since registrations can't be undone.
+var _registeredNames int
+
+func uniqueEncoderName(prefix string) string {
+	_registeredNames++
+	return fmt.Sprintf("%s-%d", prefix, _registeredNames)
+}
+
+func TestRegisterEncoders(t *testing.T) {
+	var (
+		levelName    = uniqueEncoderName("test-level")
+		timeName     = uniqueEncoderName("test-time")
+		durationName = uniqueEncoderName("test-duration")
+		callerName   = uniqueEncoderName("test-caller")
+		nameName     = uniqueEncoderName("test-name")
+	)
+	require.NoError(t, RegisterLevelEncoder(levelName, func(l Level, enc PrimitiveArrayEncoder) {
+		enc.AppendString("level-" + l.String())
+	}), "Unexpected error registering level encoder.")
+	require.NoError(t, RegisterTimeEncoder(timeName, func(t time.Time, enc PrimitiveArrayEncoder) {
+		enc.AppendInt64(t.Unix())
+	}), "Unexpected error registering time encoder.")
+	require.NoError(t, RegisterDurationEncoder(durationName, func(d time.Duration, enc PrimitiveArrayEncoder) {
+		enc.AppendInt64(int64(d / time.Minute))
+	}), "Unexpected error registering duration encoder.")
+	require.NoError(t, RegisterCallerEncoder(callerName, func(c EntryCaller, enc PrimitiveArrayEncoder) {
+		enc.AppendInt(c.Line)
+	}), "Unexpected error registering caller encoder.")
+	require.NoError(t, RegisterNameEncoder(nameName, func(name string, enc PrimitiveArrayEncoder) {
+		enc.AppendString("name-" + name)
+	}), "Unexpected error registering name encoder.")
+
+	var cfg EncoderConfig
+	require.NoError(t, yaml.Unmarshal([]byte(fmt.Sprintf(`
+levelEncoder: %s
+timeEncoder: {name: %s, timeZone: UTC}
+durationEncoder: %s
+callerEncoder: %s
+nameEncoder: %s
+`, levelName, timeName, durationName, callerName, nameName)), &cfg), "Unexpected error unmarshaling config.")
+
+	assertAppended(t, "level-info", func(arr ArrayEncoder) { cfg.EncodeLevel(InfoLevel, arr) }, "Unexpected level.")
+	assertAppended(t, int64(100), func(arr ArrayEncoder) { cfg.EncodeTime(time.Unix(100, 0), arr) }, "Unexpected time.")
+	assertAppended(t, int64(2), func(arr ArrayEncoder) { cfg.EncodeDuration(2*time.Minute, arr) }, "Unexpected duration.")
+	assertAppended(t, 42, func(arr ArrayEncoder) { cfg.EncodeCaller(EntryCaller{Line: 42}, arr) }, "Unexpected caller.")
+	assertAppended(t, "name-main", func(arr ArrayEncoder) { cfg.EncodeName("main", arr) }, "Unexpected name.")
+}
+
+func TestRegisterEncoderErrors(t *testing.T) {
+	tests := []struct {
+		desc     string
+		register func() error
+	}{
+		{"empty name", func() error { return RegisterLevelEncoder("", CapitalLevelEncoder) }},
+		{"nil encoder", func() error { return RegisterTimeEncoder("test-nil", nil) }},
+		{"built-in level", func() error { return RegisterLevelEncoder("capital", LowercaseLevelEncoder) }},
+		{"built-in time", func() error { return RegisterTimeEncoder("iso8601", EpochTimeEncoder) }},
+		{"built-in duration", func() error { return RegisterDurationEncoder("string", NanosDurationEncoder) }},
+		{"built-in caller", func() error { return RegisterCallerEncoder("full", ShortCallerEncoder) }},
+		{"built-in name", func() error { return RegisterNameEncoder("full", FullNameEncoder) }},
+	}
+
+	for _, tt := range tests {
+		assert.Error(t, tt.register(), "Expected an error registering with %s.", tt.desc)
+	}
+
+	name := uniqueEncoderName("test-twice")
+	require.NoError(t, RegisterCallerEncoder(name, FullCallerEncoder), "Unexpected error on first registration.")
+	assert.Error(t, RegisterCallerEncoder(name, ShortCallerEncoder), "Expected an error registering a name twice.")
+}
+
+func TestLookupEncoders(t *testing.T) {
+	name := uniqueEncoderName("test-lookup")
+	require.NoError(t, RegisterDurationEncoder(name, func(d time.Duration, enc PrimitiveArrayEncoder) {
+		enc.AppendInt64(int64(d / time.Hour))
+	}), "Unexpected error registering duration encoder.")
+	enc, ok := LookupDurationEncoder(name)
+	require.True(t, ok, "Expected registered name to be known.")
+	assertAppended(t, int64(3), func(arr ArrayEncoder) { enc(3*time.Hour, arr) }, "Unexpected duration.")
+
+	tests := []struct {
+		desc  string
+		known func(string) bool
+		name  string
+		want  bool
+	}{
+		{"level", func(s string) bool { _, ok := LookupLevelEncoder(s); return ok }, "capitalColor", true},
+		{"unknown level", func(s string) bool { _, ok := LookupLevelEncoder(s); return ok }, "captial", false},
+		{"time", func(s string) bool { _, ok := LookupTimeEncoder(s); return ok }, "RFC3339Nano", true},
+		{"unknown time", func(s string) bool { _, ok := LookupTimeEncoder(s); return ok }, "isoo8601", false},
+		{"duration", func(s string) bool { _, ok := LookupDurationEncoder(s); return ok }, "ms", true},
+		{"unknown duration", func(s string) bool { _, ok := LookupDurationEncoder(s); return ok }, "millis", false},
+		{"caller", func(s string) bool { _, ok := LookupCallerEncoder(s); return ok }, "full", true},
+		{"trailing caller", func(s string) bool { _, ok := LookupCallerEncoder(s); return ok }, "trailing:3", true},
+		{"unknown caller", func(s string) bool { _, ok := LookupCallerEncoder(s); return ok }, "trailing:x", false},
+		{"name", func(s string) bool { _, ok := LookupNameEncoder(s); return ok }, "full", true},
+		{"unknown name", func(s string) bool { _, ok := LookupNameEncoder(s); return ok }, "short", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.known(tt.name), "Unexpected result looking up %s encoder %q.", tt.desc, tt.name)
+	}
+}
@@ -572,6 +572,9 @@ func TestTimeEncoders(t *testing.T) {
 		{"timeEncoder: millis", 100050.005},
 		{"timeEncoder: nanos", int64(100050005000)},
 		{"timeEncoder: {layout: 06/01/02 03:04pm}", "70/01/01 12:01am"},
+		{"timeEncoder: {layout: 06/01/02 03:04pm, timeZone: Asia/Tokyo}", "70/01/01 09:01am"},
+		{"timeEncoder: {name: rfc3339, timeZone: Asia/Tokyo}", "1970-01-01T09:01:40+09:00"},
+		{"timeEncoder: {name: millis}", 100050.005},
 		{"timeEncoder: ''", 100.050005},
 		{"timeEncoder: something-random", 100.050005},
 		{"timeEncoder: rfc3339", "1970-01-01T00:01:40Z"},
@@ -595,8 +598,9 @@ func TestTimeEncoders(t *testing.T) {
 
 func TestTimeEncodersWrongYAML(t *testing.T) {
 	tests := []string{
-		"timeEncoder: [1, 2, 3]", // wrong type
-		"timeEncoder: {foo:bar",  // broken yaml
+		"timeEncoder: [1, 2, 3]",                                  // wrong type
+		"timeEncoder: {foo:bar",                                   // broken yaml
+		"timeEncoder: {name: iso8601, timeZone: Nowhere/Special}", // unknown time zone
 	}
 	for _, tt := range tests {
 		cfg := EncoderConfig{}
@@ -612,6 +616,7 @@ func TestTimeEncodersParseFromJSON(t *testing.T) {
 	}{
 		{`{"timeEncoder": "iso8601"}`, "1970-01-01T00:01:40.050Z"},
 		{`{"timeEncoder": {"layout": "06/01/02 03:04pm"}}`, "70/01/01 12:01am"},
+		{`{"timeEncoder": {"name": "iso8601", "timeZone": "UTC"}}`, "1970-01-01T00:01:40.050Z"},
 	}
 
 	for _, tt := range tests {
@@ -653,7 +658,12 @@ func TestDurationEncoders(t *testing.T) {
 }
 
 func TestCallerEncoders(t *testing.T) {
-	caller := EntryCaller{Defined: true, File: "/home/jack/src/github.com/foo/foo.go", Line: 42}
+	caller := EntryCaller{
+		Defined:  true,
+		File:     "/home/jack/src/github.com/foo/foo.go",
+		Line:     42,
+		Function: "github.com/foo.(*Foo).Bar",
+	}
 	tests := []struct {
 		name     string
 		expected interface{} // output of serializing caller
@@ -662,6 +672,11 @@ func TestCallerEncoders(t *testing.T) {
 		{"something-random", "foo/foo.go:42"},
 		{"short", "foo/foo.go:42"},
 		{"full", "/home/jack/src/github.com/foo/foo.go:42"},
+		{"function", "foo.(*Foo).Bar:42"},
+		{"module", "github.com/foo/foo.go:42"},
+		{"trailing:1", "foo.go:42"},
+		{"trailing:3", "github.com/foo/foo.go:42"},
+		{"trailing:0", "foo/foo.go:42"},
 	}
 
 	for _, tt := range tests {
@@ -64,7 +64,7 @@ func TestPutNilEntry(t *testing.T) {
 			assert.NotNil(t, ce, "Expected only non-nil CheckedEntries in pool.")
 			assert.False(t, ce.dirty, "Unexpected dirty bit set.")
 			assert.Nil(t, ce.ErrorOutput, "Non-nil ErrorOutput.")
-			assert.Equal(t, WriteThenNoop, ce.should, "Unexpected terminal behavior.")
+			assert.Nil(t, ce.after, "Unexpected terminal behavior.")
 			assert.Equal(t, 0, len(ce.cores), "Expected empty slice of cores.")
 			assert.True(t, cap(ce.cores) > 0, "Expected pooled CheckedEntries to pre-allocate slice of Cores.")
 		}
@@ -129,4 +129,26 @@ func TestCheckedEntryWrite(t *testing.T) {
 		})
 		assert.True(t, stub.Exited, "Expected to exit when WriteThenFatal is set.")
 	})
+
+	t.Run("After", func(t *testing.T) {
+		var ce *CheckedEntry
+		hook := &customHook{}
+		ce = ce.After(Entry{Message: "foo"}, hook)
+		ce.Write(Field{Key: "k", Type: Int64Type, Integer: 1})
+		assert.True(t, hook.called, "Expected to call custom hook after Write.")
+		assert.Equal(t, "foo", hook.msg, "Expected hook to see the written entry.")
+		assert.Equal(t, []Field{Field{Key: "k", Type: Int64Type, Integer: 1}}, hook.fields, "Expected hook to see the written fields.")
+	})
+}
+
+type customHook struct {
+	called bool
+	msg    string
+	fields []Field
+}
+
+func (c *customHook) OnWrite(ce *CheckedEntry, fields []Field) {
+	c.called = true
+	c.msg = ce.Message
+	c.fields = fields
 }
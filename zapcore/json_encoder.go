@@ -22,7 +22,6 @@ package zapcore
 
 import (
 	"encoding/base64"
-	"encoding/json"
 	"math"
 	"sync"
 	"time"
@@ -51,6 +50,9 @@ func putJSONEncoder(enc *jsonEncoder) {
 	enc.buf = nil
 	enc.spaced = false
 	enc.openNamespaces = 0
+	enc.nesting = 0
+	enc.array = arrayLimit{}
+	enc.keys = enc.keys[:0]
 	enc.reflectBuf = nil
 	enc.reflectEnc = nil
 	_jsonPool.Put(enc)
@@ -64,24 +66,31 @@ type jsonEncoder struct {
 
 	// for encoding generic values by reflection
 	reflectBuf *buffer.Buffer
-	reflectEnc *json.Encoder
+	reflectEnc ReflectedEncoder
+
+	// for enforcing DuplicateKeyPolicy
+	nesting int        // depth of arrays and objects being appended
+	array   arrayLimit // elements of the innermost array being appended
+	keys    []jsonKey  // keys outside of nested arrays and objects
 }
 
 // NewJSONEncoder creates a fast, low-allocation JSON encoder. The encoder
 // appropriately escapes all field keys and values.
 //
-// Note that the encoder doesn't deduplicate keys, so it's possible to produce
-// a message like
+// Note that by default the encoder doesn't deduplicate keys, so it's possible
+// to produce a message like
 //   {"foo":"bar","foo":"baz"}
 // This is permitted by the JSON specification, but not encouraged. Many
 // libraries will ignore duplicate key-value pairs (typically keeping the last
 // pair) when unmarshaling, but users should attempt to avoid adding duplicate
-// keys.
+// keys. To handle duplicates in the encoder instead, set
+// EncoderConfig.DuplicateKeys.
 func NewJSONEncoder(cfg EncoderConfig) Encoder {
 	return newJSONEncoder(cfg, false)
 }
 
 func newJSONEncoder(cfg EncoderConfig, spaced bool) *jsonEncoder {
+	cfg.ColorMode = cfg.ColorMode.withEnv()
 	return &jsonEncoder{
 		EncoderConfig: &cfg,
 		buf:           bufferpool.Get(),
@@ -136,10 +145,11 @@ func (enc *jsonEncoder) AddInt64(key string, val int64) {
 func (enc *jsonEncoder) resetReflectBuf() {
 	if enc.reflectBuf == nil {
 		enc.reflectBuf = bufferpool.Get()
-		enc.reflectEnc = json.NewEncoder(enc.reflectBuf)
-
-		// For consistency with our custom JSON encoder.
-		enc.reflectEnc.SetEscapeHTML(false)
+		newReflectedEncoder := defaultReflectedEncoder
+		if enc.EncoderConfig != nil && enc.NewReflectedEncoder != nil {
+			newReflectedEncoder = enc.NewReflectedEncoder
+		}
+		enc.reflectEnc = newReflectedEncoder(enc.reflectBuf)
 	} else {
 		enc.reflectBuf.Reset()
 	}
@@ -147,7 +157,7 @@ func (enc *jsonEncoder) resetReflectBuf() {
 
 var nullLiteralBytes = []byte("null")
 
-// Only invoke the standard JSON encoder if there is actually something to
+// Only invoke the reflected encoder if there is actually something to
 // encode; otherwise write JSON null literal directly.
 func (enc *jsonEncoder) encodeReflected(obj interface{}) ([]byte, error) {
 	if obj == nil {
@@ -193,17 +203,31 @@ func (enc *jsonEncoder) AddUint64(key string, val uint64) {
 }
 
 func (enc *jsonEncoder) AppendArray(arr ArrayMarshaler) error {
+	if enc.exceedsMaxDepth() {
+		enc.appendDepthMarker("array")
+		return nil
+	}
 	enc.addElementSeparator()
 	enc.buf.AppendByte('[')
+	enc.nesting++
+	outer := enc.openArray()
 	err := arr.MarshalLogArray(enc)
+	enc.closeArray(outer)
+	enc.nesting--
 	enc.buf.AppendByte(']')
 	return err
 }
 
 func (enc *jsonEncoder) AppendObject(obj ObjectMarshaler) error {
+	if enc.exceedsMaxDepth() {
+		enc.appendDepthMarker("object")
+		return nil
+	}
 	enc.addElementSeparator()
 	enc.buf.AppendByte('{')
+	enc.nesting++
 	err := obj.MarshalLogObject(enc)
+	enc.nesting--
 	enc.buf.AppendByte('}')
 	return err
 }
@@ -216,7 +240,11 @@ func (enc *jsonEncoder) AppendBool(val bool) {
 func (enc *jsonEncoder) AppendByteString(val []byte) {
 	enc.addElementSeparator()
 	enc.buf.AppendByte('"')
+	val, removed := truncateByteString(val, enc.maxStringLength())
 	enc.safeAddByteString(val)
+	if removed > 0 {
+		appendTruncationMarker(enc.buf, removed)
+	}
 	enc.buf.AppendByte('"')
 }
 
@@ -264,7 +292,11 @@ func (enc *jsonEncoder) AppendReflected(val interface{}) error {
 func (enc *jsonEncoder) AppendString(val string) {
 	enc.addElementSeparator()
 	enc.buf.AppendByte('"')
+	val, removed := truncateString(val, enc.maxStringLength())
 	enc.safeAddString(val)
+	if removed > 0 {
+		appendTruncationMarker(enc.buf, removed)
+	}
 	enc.buf.AppendByte('"')
 }
 
@@ -276,8 +308,16 @@ func (enc *jsonEncoder) AppendTimeLayout(time time.Time, layout string) {
 }
 
 func (enc *jsonEncoder) AppendTime(val time.Time) {
+	e := enc.EncodeFieldTime
+	if e == nil {
+		e = enc.EncodeTime
+	}
+	enc.appendTime(val, e)
+}
+
+func (enc *jsonEncoder) appendTime(val time.Time, e TimeEncoder) {
 	cur := enc.buf.Len()
-	if e := enc.EncodeTime; e != nil {
+	if e != nil {
 		e(val, enc)
 	}
 	if cur == enc.buf.Len() {
@@ -316,9 +356,14 @@ func (enc *jsonEncoder) AppendUint16(v uint16)              { enc.AppendUint64(u
 func (enc *jsonEncoder) AppendUint8(v uint8)                { enc.AppendUint64(uint64(v)) }
 func (enc *jsonEncoder) AppendUintptr(v uintptr)            { enc.AppendUint64(uint64(v)) }
 
+func (enc *jsonEncoder) colorDisabled() bool {
+	return enc.ColorMode == ColorNever
+}
+
 func (enc *jsonEncoder) Clone() Encoder {
 	clone := enc.clone()
 	clone.buf.Write(enc.buf.Bytes())
+	clone.keys = append(clone.keys, enc.keys...)
 	return clone
 }
 
@@ -332,11 +377,20 @@ func (enc *jsonEncoder) clone() *jsonEncoder {
 }
 
 func (enc *jsonEncoder) EncodeEntry(ent Entry, fields []Field) (*buffer.Buffer, error) {
+	buf, duplicates, err := enc.encodeEntry(ent, fields)
+	enc.reportDuplicateKeys(duplicates)
+	return buf, err
+}
+
+func (enc *jsonEncoder) encodeEntry(ent Entry, fields []Field) (*buffer.Buffer, []string, error) {
 	final := enc.clone()
+	// The entry's metadata is written outside of any namespaces opened on
+	// the accumulated context.
+	final.openNamespaces = 0
 	final.buf.AppendByte('{')
 
 	if final.LevelKey != "" {
-		final.addKey(final.LevelKey)
+		final.addMetadataKey(final.LevelKey)
 		cur := final.buf.Len()
 		final.EncodeLevel(ent.Level, final)
 		if cur == final.buf.Len() {
@@ -346,10 +400,11 @@ func (enc *jsonEncoder) EncodeEntry(ent Entry, fields []Field) (*buffer.Buffer,
 		}
 	}
 	if final.TimeKey != "" {
-		final.AddTime(final.TimeKey, ent.Time)
+		final.addMetadataKey(final.TimeKey)
+		final.appendTime(final.TimeZone.In(ent.Time), final.EncodeTime)
 	}
 	if ent.LoggerName != "" && final.NameKey != "" {
-		final.addKey(final.NameKey)
+		final.addMetadataKey(final.NameKey)
 		cur := final.buf.Len()
 		nameEncoder := final.EncodeName
 
@@ -368,7 +423,7 @@ func (enc *jsonEncoder) EncodeEntry(ent Entry, fields []Field) (*buffer.Buffer,
 	}
 	if ent.Caller.Defined {
 		if final.CallerKey != "" {
-			final.addKey(final.CallerKey)
+			final.addMetadataKey(final.CallerKey)
 			cur := final.buf.Len()
 			final.EncodeCaller(ent.Caller, final)
 			if cur == final.buf.Len() {
@@ -378,22 +433,46 @@ func (enc *jsonEncoder) EncodeEntry(ent Entry, fields []Field) (*buffer.Buffer,
 			}
 		}
 		if final.FunctionKey != "" {
-			final.addKey(final.FunctionKey)
+			final.addMetadataKey(final.FunctionKey)
 			final.AppendString(ent.Caller.Function)
 		}
 	}
 	if final.MessageKey != "" {
-		final.addKey(enc.MessageKey)
+		final.addMetadataKey(enc.MessageKey)
 		final.AppendString(ent.Message)
 	}
+	fieldsStart := final.buf.Len()
 	if enc.buf.Len() > 0 {
+		final.closeKeys(0)
 		final.addElementSeparator()
+		final.appendKeys(enc.keys, final.buf.Len())
 		final.buf.Write(enc.buf.Bytes())
 	}
+	final.openNamespaces = enc.openNamespaces
 	addFields(final, fields)
+	var duplicates []string
 	final.closeOpenNamespaces()
+	if final.SortKeys {
+		// Resolve duplicates while fields are still in the order they were
+		// added, since that's what the policies are defined in terms of.
+		duplicates = final.resolveDuplicateKeys()
+		final.sortFields(fieldsStart)
+	}
 	if ent.Stack != "" && final.StacktraceKey != "" {
-		final.AddString(final.StacktraceKey, ent.Stack)
+		final.addMetadataKey(final.StacktraceKey)
+		cur := final.buf.Len()
+		if final.EncodeStacktrace != nil {
+			final.EncodeStacktrace(ent.Stack, final)
+		}
+		if cur == final.buf.Len() {
+			// No stacktrace encoder, or it was a no-op. Fall back to
+			// strings to keep output JSON valid.
+			final.AppendString(ent.Stack)
+		}
+	}
+	duplicates = append(duplicates, final.resolveDuplicateKeys()...)
+	if final.MaxEntrySize > 0 {
+		final.truncateEntry()
 	}
 	final.buf.AppendByte('}')
 	if final.LineEnding != "" {
@@ -404,7 +483,7 @@ func (enc *jsonEncoder) EncodeEntry(ent Entry, fields []Field) (*buffer.Buffer,
 
 	ret := final.buf
 	putJSONEncoder(final)
-	return ret, nil
+	return ret, duplicates, nil
 }
 
 func (enc *jsonEncoder) truncate() {
@@ -412,23 +491,50 @@ func (enc *jsonEncoder) truncate() {
 }
 
 func (enc *jsonEncoder) closeOpenNamespaces() {
-	for i := 0; i < enc.openNamespaces; i++ {
+	for ; enc.openNamespaces > 0; enc.openNamespaces-- {
+		enc.closeKeys(enc.openNamespaces)
 		enc.buf.AppendByte('}')
 	}
 }
 
 func (enc *jsonEncoder) addKey(key string) {
+	track := enc.tracksKeys()
+	if track {
+		enc.closeKeys(enc.openNamespaces)
+	}
 	enc.addElementSeparator()
+	start := enc.buf.Len()
 	enc.buf.AppendByte('"')
 	enc.safeAddString(key)
 	enc.buf.AppendByte('"')
+	if track {
+		enc.keys = append(enc.keys, jsonKey{
+			key:   key,
+			scope: enc.openNamespaces,
+			start: start,
+			colon: enc.buf.Len(),
+			end:   -1,
+		})
+	}
 	enc.buf.AppendByte(':')
 	if enc.spaced {
 		enc.buf.AppendByte(' ')
 	}
 }
 
+// addMetadataKey adds a key for the entry's metadata, which duplicate key
+// policies never drop.
+func (enc *jsonEncoder) addMetadataKey(key string) {
+	enc.addKey(key)
+	if enc.tracksKeys() {
+		enc.keys[len(enc.keys)-1].meta = true
+	}
+}
+
 func (enc *jsonEncoder) addElementSeparator() {
+	if enc.array.depth != 0 && enc.array.depth == enc.nesting {
+		enc.countElement()
+	}
 	last := enc.buf.Len() - 1
 	if last < 0 {
 		return
@@ -21,10 +21,13 @@
 package zapcore_test
 
 import (
+	"encoding/json"
+	"io"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -167,3 +170,33 @@ func TestJSONEmptyConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestJSONCustomReflectedEncoder(t *testing.T) {
+	type foo struct {
+		Name string `json:"name"`
+		Tags []int  `json:"tags"`
+	}
+
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		MessageKey: "M",
+		NewReflectedEncoder: func(w io.Writer) zapcore.ReflectedEncoder {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc
+		},
+	})
+	buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, []zapcore.Field{
+		zap.Any("foo", foo{Name: "<b>", Tags: []int{1}}),
+		zap.Reflect("nil", nil),
+	})
+	require.NoError(t, err, "Unexpected JSON encoding error.")
+	defer buf.Free()
+
+	expected := `{"M":"hello","foo":{
+  "name": "\u003cb\u003e",
+  "tags": [
+    1
+  ]
+},"nil":null}` + "\n"
+	assert.Equal(t, expected, buf.String(), "Expected custom reflected encoder to be used.")
+}
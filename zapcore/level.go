@@ -112,7 +112,8 @@ func (l Level) MarshalText() ([]byte, error) {
 // example).
 //
 // In particular, this makes it easy to configure logging levels using YAML,
-// TOML, or JSON files.
+// TOML, or JSON files. Aliases registered with RegisterLevelAlias are
+// accepted too.
 func (l *Level) UnmarshalText(text []byte) error {
 	if l == nil {
 		return errUnmarshalNilLevel
@@ -124,6 +125,17 @@ func (l *Level) UnmarshalText(text []byte) error {
 }
 
 func (l *Level) unmarshalText(text []byte) bool {
+	if l.unmarshalBuiltinText(text) {
+		return true
+	}
+	alias, ok := lookupLevelAlias(string(text))
+	if ok {
+		*l = alias
+	}
+	return ok
+}
+
+func (l *Level) unmarshalBuiltinText(text []byte) bool {
 	switch string(text) {
 	case "debug", "DEBUG":
 		*l = DebugLevel
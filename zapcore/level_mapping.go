@@ -0,0 +1,231 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+var (
+	_levelAliasesMu sync.RWMutex
+	_levelAliases   = make(map[string]Level)
+)
+
+// RegisterLevelAlias makes Level.UnmarshalText, and so AtomicLevel and
+// configuration parsing, accept alias as an alternative name for the level.
+// Aliases are case-sensitive.
+//
+// Registering the same alias for the same level again is a no-op, but
+// registering an alias that already names a different level returns an
+// error.
+func RegisterLevelAlias(alias string, l Level) error {
+	return registerLevelAliases(map[Level]string{l: alias})
+}
+
+// registerLevelAliases registers an alias for each level, or none of them
+// if any conflicts with another level's name or alias.
+func registerLevelAliases(aliases map[Level]string) error {
+	levels := make([]Level, 0, len(aliases))
+	for l := range aliases {
+		levels = append(levels, l)
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+
+	_levelAliasesMu.Lock()
+	defer _levelAliasesMu.Unlock()
+	pending := make(map[string]Level, len(aliases))
+	for _, l := range levels {
+		alias := aliases[l]
+		if alias == "" {
+			return errors.New("no level alias specified")
+		}
+		var existing Level
+		if existing.unmarshalBuiltinText([]byte(alias)) && existing != l {
+			return fmt.Errorf("level alias %q already names level %v", alias, existing)
+		}
+		if existing, ok := _levelAliases[alias]; ok && existing != l {
+			return fmt.Errorf("level alias %q already registered for level %v", alias, existing)
+		}
+		if existing, ok := pending[alias]; ok {
+			return fmt.Errorf("level alias %q given for both level %v and level %v", alias, existing, l)
+		}
+		pending[alias] = l
+	}
+	for alias, l := range pending {
+		_levelAliases[alias] = l
+	}
+	return nil
+}
+
+func lookupLevelAlias(alias string) (Level, bool) {
+	_levelAliasesMu.RLock()
+	defer _levelAliasesMu.RUnlock()
+	l, ok := _levelAliases[alias]
+	return l, ok
+}
+
+// LevelEncoderAliases returns what enc serializes each Level to, for the
+// levels that enc serializes to a plain string or integer. It's meant for encoders like those built by
+// LevelEncoderOfNames, LevelEncoderOfNumbers, or a "mapping" in the
+// levelEncoder configuration.
+func LevelEncoderAliases(enc LevelEncoder) map[Level]string {
+	aliases := make(map[Level]string, _maxLevel-_minLevel+1)
+	for l := _minLevel; l <= _maxLevel; l++ {
+		arr := getSliceEncoder()
+		enc(l, arr)
+		if len(arr.elems) == 1 {
+			switch v := arr.elems[0].(type) {
+			case string:
+				aliases[l] = v
+			case int, int64, int32, int16, int8, uint, uint64, uint32, uint16, uint8:
+				aliases[l] = fmt.Sprint(v)
+			}
+		}
+		putSliceEncoder(arr)
+	}
+	return aliases
+}
+
+// RegisterLevelEncoderAliases registers the LevelEncoderAliases of enc, so
+// that output written with enc can be parsed back into Levels. Unmarshaling
+// a levelEncoder mapping doesn't register any aliases by itself; zap.Config
+// accepts the mapped values for its own level instead.
+//
+// Like RegisterLevelAlias, it returns an error if a serialized value already
+// names a different level. In that case, none of the aliases are registered.
+func RegisterLevelEncoderAliases(enc LevelEncoder) error {
+	if enc == nil {
+		return errors.New("no level encoder specified")
+	}
+	return registerLevelAliases(LevelEncoderAliases(enc))
+}
+
+// LevelEncoderOfNames returns a LevelEncoder which serializes each Level to
+// the name it's mapped to, like "WARNING" for WarnLevel. Levels missing from
+// names are serialized as LowercaseLevelEncoder does.
+//
+// To parse the names back into Levels, register them with RegisterLevelAlias
+// or RegisterLevelEncoderAliases.
+func LevelEncoderOfNames(names map[Level]string) LevelEncoder {
+	copied := make(map[Level]string, len(names))
+	for l, name := range names {
+		copied[l] = name
+	}
+	return func(l Level, enc PrimitiveArrayEncoder) {
+		if name, ok := copied[l]; ok {
+			enc.AppendString(name)
+			return
+		}
+		LowercaseLevelEncoder(l, enc)
+	}
+}
+
+// LevelEncoderOfNumbers returns a LevelEncoder which serializes each Level to
+// the integer it's mapped to, like 40 for WarnLevel in Bunyan's scale. Levels
+// missing from numbers are serialized as LowercaseLevelEncoder does.
+//
+// To parse the numbers back into Levels, register their decimal
+// representations with RegisterLevelAlias, or use
+// RegisterLevelEncoderAliases.
+func LevelEncoderOfNumbers(numbers map[Level]int) LevelEncoder {
+	copied := make(map[Level]int, len(numbers))
+	for l, n := range numbers {
+		copied[l] = n
+	}
+	return func(l Level, enc PrimitiveArrayEncoder) {
+		if n, ok := copied[l]; ok {
+			enc.AppendInt(n)
+			return
+		}
+		LowercaseLevelEncoder(l, enc)
+	}
+}
+
+// unmarshalLevelMapping builds a LevelEncoder from a mapping of level names
+// to either strings or integers, as unmarshaled from YAML or JSON. It checks
+// that the mapped values could be registered as level aliases, but doesn't
+// register them.
+func unmarshalLevelMapping(mapping map[string]interface{}) (LevelEncoder, error) {
+	if len(mapping) == 0 {
+		return nil, errors.New("empty level mapping")
+	}
+
+	names := make(map[Level]string, len(mapping))
+	numbers := make(map[Level]int, len(mapping))
+	for key, val := range mapping {
+		var l Level
+		if err := l.UnmarshalText([]byte(key)); err != nil {
+			return nil, err
+		}
+		switch v := val.(type) {
+		case string:
+			names[l] = v
+		case int:
+			numbers[l] = v
+		case float64: // from JSON
+			if v != math.Trunc(v) || v > math.MaxInt32 || v < math.MinInt32 {
+				return nil, fmt.Errorf("level mapping for %q must be a string or an integer, got %v", key, v)
+			}
+			numbers[l] = int(v)
+		default:
+			return nil, fmt.Errorf("level mapping for %q must be a string or an integer, got %v", key, v)
+		}
+	}
+	if len(names) > 0 && len(numbers) > 0 {
+		return nil, errors.New("level mapping must map all levels to strings or all to integers")
+	}
+
+	mapped := make(map[string]Level, len(mapping))
+	for l, name := range names {
+		if err := checkLevelMappingValue(mapped, name, l); err != nil {
+			return nil, err
+		}
+	}
+	for l, n := range numbers {
+		if err := checkLevelMappingValue(mapped, strconv.Itoa(n), l); err != nil {
+			return nil, err
+		}
+	}
+	if len(names) > 0 {
+		return LevelEncoderOfNames(names), nil
+	}
+	return LevelEncoderOfNumbers(numbers), nil
+}
+
+// checkLevelMappingValue returns an error if a mapped value would be
+// ambiguous as a level alias: if it's the name of a different built-in level,
+// or if another level in the same mapping uses it.
+func checkLevelMappingValue(mapped map[string]Level, alias string, l Level) error {
+	var builtin Level
+	if builtin.unmarshalBuiltinText([]byte(alias)) && builtin != l {
+		return fmt.Errorf("level mapping value %q already names level %v", alias, builtin)
+	}
+	if other, ok := mapped[alias]; ok && other != l {
+		return fmt.Errorf("level mapping value %q used for both %v and %v", alias, other, l)
+	}
+	mapped[alias] = l
+	return nil
+}
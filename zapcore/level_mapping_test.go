@@ -0,0 +1,142 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	. "go.uber.org/zap/zapcore"
+)
+
+func TestLevelEncoderOfMapping(t *testing.T) {
+	names := LevelEncoderOfNames(map[Level]string{WarnLevel: "WARNING", FatalLevel: "critical"})
+	assertAppended(t, "WARNING", func(arr ArrayEncoder) { names(WarnLevel, arr) }, "Unexpected mapped name.")
+	assertAppended(t, "critical", func(arr ArrayEncoder) { names(FatalLevel, arr) }, "Unexpected mapped name.")
+	assertAppended(t, "info", func(arr ArrayEncoder) { names(InfoLevel, arr) }, "Expected unmapped level to fall back.")
+
+	numbers := LevelEncoderOfNumbers(map[Level]int{InfoLevel: 30})
+	assertAppended(t, 30, func(arr ArrayEncoder) { numbers(InfoLevel, arr) }, "Unexpected mapped number.")
+	assertAppended(t, "error", func(arr ArrayEncoder) { numbers(ErrorLevel, arr) }, "Expected unmapped level to fall back.")
+}
+
+func TestRegisterLevelAlias(t *testing.T) {
+	require.NoError(t, RegisterLevelAlias("test-loud", ErrorLevel), "Unexpected error registering alias.")
+	require.NoError(t, RegisterLevelAlias("test-loud", ErrorLevel), "Expected re-registering the same alias to succeed.")
+	require.NoError(t, RegisterLevelAlias("WARN", WarnLevel), "Expected aliasing a built-in name to its own level to succeed.")
+
+	var l Level
+	require.NoError(t, l.UnmarshalText([]byte("test-loud")), "Unexpected error parsing alias.")
+	assert.Equal(t, ErrorLevel, l, "Unexpected level for alias.")
+
+	assert.Error(t, RegisterLevelAlias("", InfoLevel), "Expected error registering empty alias.")
+	assert.Error(t, RegisterLevelAlias("test-loud", WarnLevel), "Expected error re-registering alias for another level.")
+	assert.Error(t, RegisterLevelAlias("info", WarnLevel), "Expected error aliasing a built-in name to another level.")
+	assert.Error(t, l.UnmarshalText([]byte("test-quiet")), "Expected error parsing unregistered alias.")
+}
+
+func TestLevelEncoderMappingParse(t *testing.T) {
+	var fromYAML EncoderConfig
+	require.NoError(t, yaml.Unmarshal(
+		[]byte("levelEncoder: {mapping: {warn: test-WARNING, fatal: test-critical}}"),
+		&fromYAML,
+	), "Unexpected error unmarshaling YAML.")
+	assertAppended(t, "test-WARNING", func(arr ArrayEncoder) { fromYAML.EncodeLevel(WarnLevel, arr) }, "Unexpected level from YAML mapping.")
+
+	var l Level
+	require.NoError(t, RegisterLevelEncoderAliases(fromYAML.EncodeLevel), "Unexpected error registering aliases.")
+	require.NoError(t, l.UnmarshalText([]byte("test-critical")), "Expected mapped name to be parseable.")
+	assert.Equal(t, FatalLevel, l, "Unexpected level for mapped name.")
+
+	var fromJSON EncoderConfig
+	require.NoError(t, json.Unmarshal(
+		[]byte(`{"levelEncoder": {"mapping": {"debug": 1020, "info": 1030}}}`),
+		&fromJSON,
+	), "Unexpected error unmarshaling JSON.")
+	assertAppended(t, 1030, func(arr ArrayEncoder) { fromJSON.EncodeLevel(InfoLevel, arr) }, "Unexpected level from JSON mapping.")
+	require.NoError(t, RegisterLevelEncoderAliases(fromJSON.EncodeLevel), "Unexpected error registering aliases.")
+	require.NoError(t, l.UnmarshalText([]byte("1020")), "Expected mapped number to be parseable.")
+	assert.Equal(t, DebugLevel, l, "Unexpected level for mapped number.")
+
+	var fromText EncoderConfig
+	require.NoError(t, yaml.Unmarshal([]byte("levelEncoder: capital"), &fromText), "Unexpected error unmarshaling name.")
+	assertAppended(t, "INFO", func(arr ArrayEncoder) { fromText.EncodeLevel(InfoLevel, arr) }, "Unexpected level from name.")
+
+	for _, doc := range []string{
+		`{"levelEncoder": {"mapping": {}}}`,
+		`{"levelEncoder": {"mapping": {"nope": "x"}}}`,
+		`{"levelEncoder": {"mapping": {"info": "x", "warn": 40}}}`,
+		`{"levelEncoder": {"mapping": {"info": 1.5}}}`,
+		`{"levelEncoder": {"mapping": {"info": true}}}`,
+		`{"levelEncoder": {"mapping": {"info": "warn"}}}`,
+		`{"levelEncoder": {"mapping": {"info": 30, "warn": 30}}}`,
+	} {
+		assert.Error(t, json.Unmarshal([]byte(doc), &EncoderConfig{}), "Expected error unmarshaling %s.", doc)
+	}
+}
+
+func TestLevelEncoderMappingReparse(t *testing.T) {
+	// Unmarshaling a mapping mustn't change global state, so a later
+	// configuration can remap the same values.
+	for _, doc := range []string{
+		`{"levelEncoder": {"mapping": {"info": 1130, "warn": 1140}}}`,
+		`{"levelEncoder": {"mapping": {"info": 1120, "warn": 1130}}}`,
+		`{"levelEncoder": {"mapping": {"info": 1130, "warn": 1140}}}`,
+	} {
+		var cfg EncoderConfig
+		assert.NoError(t, json.Unmarshal([]byte(doc), &cfg), "Unexpected error unmarshaling %s.", doc)
+	}
+
+	var l Level
+	assert.Error(t, l.UnmarshalText([]byte("1130")), "Expected unmarshaling not to register aliases.")
+}
+
+func TestRegisterLevelEncoderAliasesErrors(t *testing.T) {
+	assert.Error(t, RegisterLevelEncoderAliases(nil), "Expected error registering nil encoder.")
+	assert.Error(t, RegisterLevelEncoderAliases(LevelEncoderOfNames(map[Level]string{
+		InfoLevel: "error",
+	})), "Expected error registering an encoder that writes another level's name.")
+}
+
+func TestRegisterLevelEncoderAliasesAtomically(t *testing.T) {
+	require.NoError(t, RegisterLevelAlias("test-atomic-taken", ErrorLevel), "Unexpected error registering alias.")
+
+	enc := LevelEncoderOfNames(map[Level]string{
+		DebugLevel: "test-atomic-debug",
+		InfoLevel:  "test-atomic-info",
+		WarnLevel:  "test-atomic-taken",
+		FatalLevel: "test-atomic-fatal",
+	})
+	assert.Equal(t, "test-atomic-taken", LevelEncoderAliases(enc)[WarnLevel], "Unexpected alias for mapped level.")
+	assert.Equal(t, "error", LevelEncoderAliases(enc)[ErrorLevel], "Expected unmapped levels to use their names.")
+
+	for i := 0; i < 10; i++ {
+		assert.Error(t, RegisterLevelEncoderAliases(enc), "Expected error registering a conflicting alias.")
+		for _, alias := range []string{"test-atomic-debug", "test-atomic-info", "test-atomic-fatal"} {
+			var l Level
+			assert.Error(t, l.UnmarshalText([]byte(alias)), "Expected conflict to keep %q unregistered.", alias)
+		}
+	}
+}
@@ -21,6 +21,7 @@
 package zapcore
 
 import (
+	"fmt"
 	"time"
 
 	"go.uber.org/atomic"
@@ -125,6 +126,78 @@ func SamplerHook(hook func(entry Entry, dec SamplingDecision)) SamplerOption {
 	})
 }
 
+// KeepSampledTraces configures a Sampler to keep every entry that belongs to
+// a sampled distributed trace, so that the logs of sampled traces are
+// complete. An entry belongs to a trace if its Trace is set, or if the Core's
+// context includes a field carrying a TraceContext; see zap.Trace and
+// zap.AddTraceContext. Entries kept this way aren't counted towards the
+// per-message limits, and other entries are sampled as usual.
+func KeepSampledTraces() SamplerOption {
+	return optionFunc(func(s *sampler) {
+		s.keepTraces = true
+	})
+}
+
+// SamplerControls configures a Sampler to take its tick and its first and
+// thereafter limits from c, so that they can be changed while the program is
+// running, and to count its decisions in c. Applying the option sets c's
+// parameters to the arguments of NewSamplerWithOptions.
+func SamplerControls(c *SamplerControl) SamplerOption {
+	return optionFunc(func(s *sampler) {
+		c.store(s.tick, s.first, s.thereafter)
+		s.control = c
+	})
+}
+
+// A SamplerControl holds the parameters of a Sampler built with the
+// SamplerControls option, and counts the Sampler's decisions. It's safe for
+// concurrent use.
+type SamplerControl struct {
+	tick       atomic.Int64 // nanoseconds
+	first      atomic.Uint64
+	thereafter atomic.Uint64
+
+	sampled atomic.Uint64
+	dropped atomic.Uint64
+}
+
+// NewSamplerControl creates a SamplerControl. Its parameters are set when
+// it's passed to NewSamplerWithOptions.
+func NewSamplerControl() *SamplerControl {
+	return &SamplerControl{}
+}
+
+// Params returns the sampling parameters.
+func (c *SamplerControl) Params() (tick time.Duration, first, thereafter int) {
+	return time.Duration(c.tick.Load()), int(c.first.Load()), int(c.thereafter.Load())
+}
+
+// SetParams changes the sampling parameters. Changes to the tick apply once
+// the current tick of each level and message ends.
+func (c *SamplerControl) SetParams(tick time.Duration, first, thereafter int) error {
+	if tick <= 0 || first < 0 || thereafter <= 0 {
+		return fmt.Errorf("invalid sampling parameters: tick %v, first %v, thereafter %v", tick, first, thereafter)
+	}
+	c.store(tick, uint64(first), uint64(thereafter))
+	return nil
+}
+
+func (c *SamplerControl) store(tick time.Duration, first, thereafter uint64) {
+	c.tick.Store(int64(tick))
+	c.first.Store(first)
+	c.thereafter.Store(thereafter)
+}
+
+// Sampled returns the number of entries sampled since c was created.
+func (c *SamplerControl) Sampled() uint64 {
+	return c.sampled.Load()
+}
+
+// Dropped returns the number of entries dropped since c was created.
+func (c *SamplerControl) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
 // NewSamplerWithOptions creates a Core that samples incoming entries, which
 // caps the CPU and I/O load of logging while attempting to preserve a
 // representative subset of your logs.
@@ -162,6 +235,11 @@ type sampler struct {
 	tick              time.Duration
 	first, thereafter uint64
 	hook              func(Entry, SamplingDecision)
+
+	keepTraces   bool
+	traceSampled bool // context includes a sampled TraceContext
+
+	control *SamplerControl
 }
 
 // NewSampler creates a Core that samples incoming entries, which
@@ -182,13 +260,20 @@ func NewSampler(core Core, tick time.Duration, first, thereafter int) Core {
 }
 
 func (s *sampler) With(fields []Field) Core {
+	traceSampled := s.traceSampled
+	if s.keepTraces {
+		traceSampled = sampledTrace(fields, traceSampled)
+	}
 	return &sampler{
-		Core:       s.Core.With(fields),
-		tick:       s.tick,
-		counts:     s.counts,
-		first:      s.first,
-		thereafter: s.thereafter,
-		hook:       s.hook,
+		Core:         s.Core.With(fields),
+		tick:         s.tick,
+		counts:       s.counts,
+		first:        s.first,
+		thereafter:   s.thereafter,
+		hook:         s.hook,
+		keepTraces:   s.keepTraces,
+		traceSampled: traceSampled,
+		control:      s.control,
 	}
 }
 
@@ -197,14 +282,37 @@ func (s *sampler) Check(ent Entry, ce *CheckedEntry) *CheckedEntry {
 		return ce
 	}
 
+	if s.traceSampled || s.keepTraces && ent.Trace != nil && ent.Trace.IsSampled() {
+		s.decide(ent, LogSampled)
+		return s.Core.Check(ent, ce)
+	}
+
 	if ent.Level >= _minLevel && ent.Level <= _maxLevel {
+		tick, first, thereafter := s.tick, s.first, s.thereafter
+		if c := s.control; c != nil {
+			tick = time.Duration(c.tick.Load())
+			first, thereafter = c.first.Load(), c.thereafter.Load()
+		}
+
 		counter := s.counts.get(ent.Level, ent.Message)
-		n := counter.IncCheckReset(ent.Time, s.tick)
-		if n > s.first && (n-s.first)%s.thereafter != 0 {
-			s.hook(ent, LogDropped)
+		n := counter.IncCheckReset(ent.Time, tick)
+		if n > first && (n-first)%thereafter != 0 {
+			s.decide(ent, LogDropped)
 			return ce
 		}
-		s.hook(ent, LogSampled)
+		s.decide(ent, LogSampled)
 	}
 	return s.Core.Check(ent, ce)
 }
+
+// decide reports a sampling decision to the hook and the SamplerControl.
+func (s *sampler) decide(ent Entry, dec SamplingDecision) {
+	if c := s.control; c != nil {
+		if dec&LogDropped != 0 {
+			c.dropped.Inc()
+		} else {
+			c.sampled.Inc()
+		}
+	}
+	s.hook(ent, dec)
+}
@@ -87,6 +87,95 @@ func TestSampler(t *testing.T) {
 	}
 }
 
+type fakeTrace struct{ sampled bool }
+
+func (fakeTrace) TraceID() string   { return "4bf92f3577b34da6a3ce929d0e0e4736" }
+func (fakeTrace) SpanID() string    { return "00f067aa0ba902b7" }
+func (t fakeTrace) IsSampled() bool { return t.sampled }
+
+func TestSamplerKeepSampledTraces(t *testing.T) {
+	traceField := func(sampled bool) Field {
+		return Field{Type: ReflectType, Key: "trace", Interface: fakeTrace{sampled}}
+	}
+
+	var sampledCount, droppedCount int
+	hook := SamplerHook(func(_ Entry, dec SamplingDecision) {
+		if dec&LogSampled > 0 {
+			sampledCount++
+		}
+		if dec&LogDropped > 0 {
+			droppedCount++
+		}
+	})
+	core, logs := observer.New(DebugLevel)
+	sampler := NewSamplerWithOptions(core, time.Minute, 2, 3, KeepSampledTraces(), hook)
+
+	sampled := sampler.With([]Field{traceField(true)})
+	for i := 1; i < 10; i++ {
+		writeSequence(sampled, i, InfoLevel)
+	}
+	assert.Len(t, logs.TakeAll(), 9, "Expected all entries of a sampled trace.")
+	assert.Equal(t, 9, sampledCount, "Expected hook to see sampled entries.")
+
+	// Entries of sampled traces don't count towards the limits.
+	for i := 1; i < 10; i++ {
+		writeSequence(sampler, i, InfoLevel)
+	}
+	assertSequence(t, logs.TakeAll(), InfoLevel, 1, 2, 5, 8)
+
+	for lvl, core := range map[Level]Core{
+		WarnLevel:  sampler.With([]Field{traceField(false)}),
+		ErrorLevel: sampled.With([]Field{traceField(false)}),
+	} {
+		for i := 1; i < 10; i++ {
+			writeSequence(core, i, lvl)
+		}
+		assert.Len(t, logs.TakeAll(), 4, "Expected entries of unsampled traces to be sampled as usual.")
+	}
+	assert.Equal(t, 15, droppedCount, "Unexpected number of dropped entries.")
+}
+
+func TestSamplerControls(t *testing.T) {
+	control := NewSamplerControl()
+	core, logs := observer.New(DebugLevel)
+	sampler := NewSamplerWithOptions(core, time.Minute, 2, 3, SamplerControls(control))
+
+	tick, first, thereafter := control.Params()
+	assert.Equal(t, time.Minute, tick, "Unexpected initial tick.")
+	assert.Equal(t, 2, first, "Unexpected initial first.")
+	assert.Equal(t, 3, thereafter, "Unexpected initial thereafter.")
+
+	for i := 1; i < 10; i++ {
+		writeSequence(sampler, i, InfoLevel)
+	}
+	assertSequence(t, logs.TakeAll(), InfoLevel, 1, 2, 5, 8)
+	assert.Equal(t, uint64(4), control.Sampled(), "Unexpected sampled count.")
+	assert.Equal(t, uint64(5), control.Dropped(), "Unexpected dropped count.")
+
+	// Changes apply to existing samplers and their children.
+	require.NoError(t, control.SetParams(time.Minute, 4, 1), "Unexpected error setting parameters.")
+	for i := 1; i < 10; i++ {
+		writeSequence(sampler, i, WarnLevel)
+	}
+	assertSequence(t, logs.TakeAll(), WarnLevel, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	assert.Equal(t, uint64(13), control.Sampled(), "Unexpected sampled count.")
+
+	for _, params := range [][3]int{{0, 1, 1}, {1, -1, 1}, {1, 1, 0}} {
+		assert.Error(t, control.SetParams(time.Duration(params[0]), params[1], params[2]), "Expected error for invalid parameters %v.", params)
+	}
+	_, first, _ = control.Params()
+	assert.Equal(t, 4, first, "Unexpected change after invalid parameters.")
+}
+
+func TestSamplerIgnoresTracesByDefault(t *testing.T) {
+	sampler, logs := fakeSampler(DebugLevel, time.Minute, 2, 3)
+	sampled := sampler.With([]Field{{Type: ReflectType, Key: "trace", Interface: fakeTrace{true}}})
+	for i := 1; i < 10; i++ {
+		writeSequence(sampled, i, InfoLevel)
+	}
+	assert.Len(t, logs.TakeAll(), 4, "Expected traces to be sampled as usual without KeepSampledTraces.")
+}
+
 func TestSamplerDisabledLevels(t *testing.T) {
 	sampler, logs := fakeSampler(InfoLevel, time.Minute, 1, 100)
 
@@ -0,0 +1,222 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"bytes"
+	"sort"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/internal/bufferpool"
+)
+
+// sortFields reorders the fields encoded from offset start onward by key,
+// recursing into nested objects and arrays, so that output doesn't depend on
+// the order in which fields were added. Keys are compared as encoded.
+//
+// Top-level key records are rebuilt to match the new layout; records for keys
+// inside namespaces are dropped, since duplicates among them have already been
+// resolved. If the fields can't be parsed, which can only happen with a
+// misbehaving ReflectedEncoder, they're left as-is.
+func (enc *jsonEncoder) sortFields(start int) {
+	src := enc.buf.Bytes()[start:]
+	if len(src) == 0 {
+		return
+	}
+
+	s := jsonSorter{src: src, elemSep: ","}
+	if enc.spaced {
+		s.elemSep = ", "
+	}
+	out := bufferpool.Get()
+	defer out.Free()
+
+	// Fields follow the entry's metadata, so keep any leading separator.
+	i := s.skipSpace(0)
+	out.Write(src[:i])
+	var members []jsonMember
+	i, members = s.appendMembers(out, i)
+	if s.failed || i != len(src) {
+		return
+	}
+
+	if enc.tracksKeys() {
+		names := make(map[int]string, len(enc.keys))
+		for _, k := range enc.keys {
+			if k.scope == 0 {
+				names[k.start-start] = k.key
+			}
+		}
+		enc.keys = enc.keys[:0]
+		for _, m := range members {
+			enc.keys = append(enc.keys, jsonKey{
+				key:   names[m.srcStart],
+				start: start + m.start,
+				colon: start + m.colon,
+				end:   start + m.end,
+			})
+		}
+	}
+	enc.buf.Truncate(start)
+	enc.buf.Write(out.Bytes())
+}
+
+// jsonMember is an object member, sorted and copied by jsonSorter.
+type jsonMember struct {
+	key      []byte // encoded key, without quotes
+	srcStart int    // offset of the key's opening quote in the source
+	start    int    // offset of the key's opening quote in the output
+	colon    int    // offset just past the key's closing quote in the output
+	end      int    // offset just past the value in the output
+}
+
+// _jsonDelimiters end a scalar JSON value.
+var _jsonDelimiters = []byte(",:{}[] \t\n\r")
+
+// jsonSorter copies JSON written by jsonEncoder, sorting object members.
+type jsonSorter struct {
+	src     []byte
+	elemSep string
+	failed  bool
+}
+
+func (s *jsonSorter) skipSpace(i int) int {
+	for i < len(s.src) {
+		switch s.src[i] {
+		case ',', ' ', '\t', '\n', '\r':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// skipString returns the offset just past the string starting at i.
+func (s *jsonSorter) skipString(i int) int {
+	for i++; i < len(s.src); i++ {
+		switch s.src[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	s.failed = true
+	return len(s.src)
+}
+
+// appendMembers copies the object members starting at i, up to the closing
+// brace or the end of the source, in sorted order. It returns the offset
+// where it stopped and the members' positions in out.
+func (s *jsonSorter) appendMembers(out *buffer.Buffer, i int) (int, []jsonMember) {
+	scratch := bufferpool.Get()
+	defer scratch.Free()
+
+	var members []jsonMember
+	for i = s.skipSpace(i); i < len(s.src) && s.src[i] != '}' && !s.failed; i = s.skipSpace(i) {
+		if s.src[i] != '"' {
+			s.failed = true
+			break
+		}
+		m := jsonMember{srcStart: i, start: scratch.Len()}
+		colon := s.skipString(i)
+		m.key = s.src[i+1 : colon-1]
+		valueStart := colon
+		for valueStart < len(s.src) && (s.src[valueStart] == ':' || s.src[valueStart] == ' ') {
+			valueStart++
+		}
+		scratch.Write(s.src[i:valueStart])
+		m.colon = m.start + colon - i
+		i = s.appendValue(scratch, valueStart)
+		m.end = scratch.Len()
+		members = append(members, m)
+	}
+	if s.failed {
+		return i, nil
+	}
+
+	sort.SliceStable(members, func(a, b int) bool {
+		return bytes.Compare(members[a].key, members[b].key) < 0
+	})
+	bs := scratch.Bytes()
+	for j := range members {
+		if j > 0 {
+			out.AppendString(s.elemSep)
+		}
+		m := &members[j]
+		start := out.Len()
+		out.Write(bs[m.start:m.end])
+		m.colon += start - m.start
+		m.start, m.end = start, out.Len()
+	}
+	return i, members
+}
+
+// appendValue copies the value starting at i, sorting any objects within it,
+// and returns the offset just past it.
+func (s *jsonSorter) appendValue(out *buffer.Buffer, i int) int {
+	if i >= len(s.src) {
+		s.failed = true
+		return i
+	}
+	switch s.src[i] {
+	case '{':
+		out.AppendByte('{')
+		i, _ = s.appendMembers(out, i+1)
+		if i >= len(s.src) {
+			s.failed = true
+			return i
+		}
+		out.AppendByte('}')
+		return i + 1
+	case '[':
+		out.AppendByte('[')
+		first := true
+		for i = s.skipSpace(i + 1); i < len(s.src) && s.src[i] != ']' && !s.failed; i = s.skipSpace(i) {
+			if !first {
+				out.AppendString(s.elemSep)
+			}
+			first = false
+			i = s.appendValue(out, i)
+		}
+		if i >= len(s.src) {
+			s.failed = true
+			return i
+		}
+		out.AppendByte(']')
+		return i + 1
+	case '"':
+		end := s.skipString(i)
+		out.Write(s.src[i:end])
+		return end
+	default:
+		end := i
+		for end < len(s.src) && bytes.IndexByte(_jsonDelimiters, s.src[end]) < 0 {
+			end++
+		}
+		if end == i {
+			s.failed = true
+		}
+		out.Write(s.src[i:end])
+		return end
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.uber.org/zap"
+	. "go.uber.org/zap/zapcore"
+)
+
+func TestSortKeys(t *testing.T) {
+	obj := zap.Object("obj", ObjectMarshalerFunc(func(enc ObjectEncoder) error {
+		enc.AddString("z", "}{\",")
+		enc.AddInt("a", 1)
+		return enc.AddArray("m", ArrayMarshalerFunc(func(enc ArrayEncoder) error {
+			return enc.AppendObject(ObjectMarshalerFunc(func(enc ObjectEncoder) error {
+				enc.AddBool("y", true)
+				enc.AddBool("x", false)
+				return nil
+			}))
+		}))
+	}))
+
+	tests := []struct {
+		desc            string
+		configure       func(*EncoderConfig)
+		context         []Field
+		fields          []Field
+		stack           string
+		expectedJSON    string
+		expectedConsole string
+	}{
+		{
+			desc:            "fields",
+			context:         []Field{zap.Int("c", 3), zap.Int("a", 1)},
+			fields:          []Field{zap.Int("b", 2)},
+			expectedJSON:    `{"L":"info","M":"hello","a":1,"b":2,"c":3}`,
+			expectedConsole: `info	hello	{"a": 1, "b": 2, "c": 3}`,
+		},
+		{
+			desc:            "namespaces",
+			context:         []Field{zap.Int("z", 1), zap.Namespace("ns"), zap.Int("c", 3)},
+			fields:          []Field{zap.Int("b", 2)},
+			expectedJSON:    `{"L":"info","M":"hello","ns":{"b":2,"c":3},"z":1}`,
+			expectedConsole: `info	hello	{"ns": {"b": 2, "c": 3}, "z": 1}`,
+		},
+		{
+			desc:            "nested objects",
+			fields:          []Field{obj, zap.Any("any", map[string]int{"k": 1}), zap.Strings("s", []string{"b", "a"})},
+			expectedJSON:    `{"L":"info","M":"hello","any":{"k":1},"obj":{"a":1,"m":[{"x":false,"y":true}],"z":"}{\","},"s":["b","a"]}`,
+			expectedConsole: `info	hello	{"any": {"k":1}, "obj": {"a": 1, "m": [{"x": false, "y": true}], "z": "}{\","}, "s": ["b", "a"]}`,
+		},
+		{
+			desc:            "stacktrace stays last",
+			fields:          []Field{zap.Int("b", 2), zap.Int("a", 1)},
+			stack:           "stack",
+			expectedJSON:    `{"L":"info","M":"hello","a":1,"b":2,"S":"stack"}`,
+			expectedConsole: "info\thello\t{\"a\": 1, \"b\": 2}\nstack",
+		},
+		{
+			desc:            "duplicates resolved in call order",
+			configure:       func(c *EncoderConfig) { c.DuplicateKeys = KeepLastDuplicateKey },
+			context:         []Field{zap.Int("b", 1), zap.Int("a", 1)},
+			fields:          []Field{zap.Int("b", 2)},
+			expectedJSON:    `{"L":"info","M":"hello","a":1,"b":2}`,
+			expectedConsole: `info	hello	{"a": 1, "b": 2}`,
+		},
+		{
+			desc:            "entry size",
+			configure:       func(c *EncoderConfig) { c.MaxEntrySize = 80 },
+			fields:          []Field{zap.String("b", strings.Repeat("x", 60)), zap.Int("a", 1)},
+			expectedJSON:    `{"L":"info","M":"hello","a":1,"truncated":"…(truncated 67B)"}`,
+			expectedConsole: `info	hello	{"a": 1, "b": "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxx…(truncated 32B)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			cfg := EncoderConfig{
+				LevelKey:      "L",
+				MessageKey:    "M",
+				StacktraceKey: "S",
+				EncodeLevel:   LowercaseLevelEncoder,
+				SortKeys:      true,
+			}
+			if tt.configure != nil {
+				tt.configure(&cfg)
+			}
+
+			encoders := []struct {
+				enc      Encoder
+				expected string
+			}{
+				{NewJSONEncoder(cfg), tt.expectedJSON},
+				{NewConsoleEncoder(cfg), tt.expectedConsole},
+			}
+			for _, e := range encoders {
+				for _, f := range tt.context {
+					f.AddTo(e.enc)
+				}
+				ent := Entry{Level: InfoLevel, Message: "hello", Stack: tt.stack}
+				buf, err := e.enc.EncodeEntry(ent, tt.fields)
+				require.NoError(t, err, "Unexpected error encoding entry.")
+				assert.Equal(t, e.expected+"\n", buf.String(), "Unexpected output.")
+				buf.Free()
+			}
+		})
+	}
+}
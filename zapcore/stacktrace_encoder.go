@@ -0,0 +1,237 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/internal/bufferpool"
+)
+
+// A StacktraceEncoder serializes an entry's stacktrace, in the format
+// described by StackFrame, to a primitive type.
+type StacktraceEncoder func(string, PrimitiveArrayEncoder)
+
+// StackFrame is one frame of a stacktrace. In an entry's Stack, each frame
+// is written as the function's name, followed by a line with a tab, the file,
+// a colon, and the line number; frames are separated by newlines.
+type StackFrame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// MarshalLogObject implements ObjectMarshaler.
+func (f StackFrame) MarshalLogObject(enc ObjectEncoder) error {
+	enc.AddString("function", f.Function)
+	enc.AddString("file", f.File)
+	enc.AddInt("line", f.Line)
+	return nil
+}
+
+// ParseStacktrace splits a stacktrace into its frames. It returns false if
+// the stacktrace isn't in the format described by StackFrame.
+func ParseStacktrace(stack string) ([]StackFrame, bool) {
+	lines := strings.Split(stack, "\n")
+	if len(lines)%2 != 0 {
+		return nil, false
+	}
+	frames := make([]StackFrame, 0, len(lines)/2)
+	for i := 0; i < len(lines); i += 2 {
+		loc := lines[i+1]
+		colon := strings.LastIndexByte(loc, ':')
+		if !strings.HasPrefix(loc, "\t") || colon < 0 {
+			return nil, false
+		}
+		line, err := strconv.Atoi(loc[colon+1:])
+		if err != nil {
+			return nil, false
+		}
+		frames = append(frames, StackFrame{
+			Function: lines[i],
+			File:     loc[1:colon],
+			Line:     line,
+		})
+	}
+	return frames, true
+}
+
+// StringStacktraceEncoder serializes a stacktrace as-is, as a single string.
+func StringStacktraceEncoder(stack string, enc PrimitiveArrayEncoder) {
+	enc.AppendString(stack)
+}
+
+// FramesStacktraceEncoder serializes a stacktrace as an array of objects with
+// "function", "file", and "line" keys, so that log backends can index it.
+// It's equivalent to StacktraceEncoderOfFrames(StacktraceFramesConfig{}).
+func FramesStacktraceEncoder(stack string, enc PrimitiveArrayEncoder) {
+	encodeStackFrames(stack, StacktraceFramesConfig{}, enc)
+}
+
+// StacktraceFramesConfig limits the frames written by
+// StacktraceEncoderOfFrames, or captured by a Logger configured with
+// zap.StacktraceFrames.
+type StacktraceFramesConfig struct {
+	// MaxDepth caps the number of frames written, after filtering. Zero
+	// writes every frame.
+	MaxDepth int `json:"maxDepth" yaml:"maxDepth"`
+	// SkipRuntime drops frames from the Go runtime.
+	SkipRuntime bool `json:"skipRuntime" yaml:"skipRuntime"`
+	// SkipZap drops frames from Zap itself.
+	SkipZap bool `json:"skipZap" yaml:"skipZap"`
+	// SkipPrefixes drops frames whose function name, including its package
+	// path, starts with any of these prefixes.
+	SkipPrefixes []string `json:"skipPrefixes" yaml:"skipPrefixes"`
+}
+
+// Filters reports whether the config drops any frames other than those past
+// MaxDepth.
+func (c StacktraceFramesConfig) Filters() bool {
+	return c.SkipRuntime || c.SkipZap || len(c.SkipPrefixes) > 0
+}
+
+// Skips reports whether the config drops frames of the named function,
+// including its package path, as stack frames and runtime.Frame report it.
+func (c StacktraceFramesConfig) Skips(function string) bool {
+	if c.SkipRuntime && strings.HasPrefix(function, "runtime.") {
+		return true
+	}
+	if c.SkipZap && (strings.HasPrefix(function, "go.uber.org/zap.") || strings.HasPrefix(function, "go.uber.org/zap/")) {
+		return true
+	}
+	for _, p := range c.SkipPrefixes {
+		if strings.HasPrefix(function, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// StacktraceEncoderOfFrames returns a StacktraceEncoder which works like
+// FramesStacktraceEncoder, but filters and limits the frames it writes.
+func StacktraceEncoderOfFrames(cfg StacktraceFramesConfig) StacktraceEncoder {
+	cfg.SkipPrefixes = append([]string(nil), cfg.SkipPrefixes...)
+	return func(stack string, enc PrimitiveArrayEncoder) {
+		encodeStackFrames(stack, cfg, enc)
+	}
+}
+
+type stackFrames []StackFrame
+
+func (fs stackFrames) MarshalLogArray(enc ArrayEncoder) error {
+	for _, f := range fs {
+		if err := enc.AppendObject(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeStackFrames writes the filtered frames of a stacktrace as an array if
+// enc supports AppendArray, and as a string in the usual format otherwise.
+// Stacktraces that can't be parsed are written as-is.
+func encodeStackFrames(stack string, cfg StacktraceFramesConfig, enc PrimitiveArrayEncoder) {
+	type appendArrayEncoder interface {
+		AppendArray(ArrayMarshaler) error
+	}
+
+	all, ok := ParseStacktrace(stack)
+	if !ok {
+		enc.AppendString(stack)
+		return
+	}
+	frames := all[:0]
+	for _, f := range all {
+		if cfg.MaxDepth > 0 && len(frames) == cfg.MaxDepth {
+			break
+		}
+		if !cfg.Skips(f.Function) {
+			frames = append(frames, f)
+		}
+	}
+
+	if enc, ok := enc.(appendArrayEncoder); ok {
+		_ = enc.AppendArray(stackFrames(frames))
+		return
+	}
+
+	buf := bufferpool.Get()
+	for i, f := range frames {
+		if i > 0 {
+			buf.AppendByte('\n')
+		}
+		buf.AppendString(f.Function)
+		buf.AppendString("\n\t")
+		buf.AppendString(f.File)
+		buf.AppendByte(':')
+		buf.AppendInt(int64(f.Line))
+	}
+	enc.AppendString(buf.String())
+	buf.Free()
+}
+
+// UnmarshalText unmarshals text to a StacktraceEncoder. "frames" is
+// unmarshaled to FramesStacktraceEncoder, and anything else is unmarshaled
+// to StringStacktraceEncoder.
+func (e *StacktraceEncoder) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "frames":
+		*e = FramesStacktraceEncoder
+	default:
+		*e = StringStacktraceEncoder
+	}
+	return nil
+}
+
+// UnmarshalYAML unmarshals YAML to a StacktraceEncoder.
+// If value is an object, it's unmarshaled to a StacktraceFramesConfig and
+// then to an encoder with StacktraceEncoderOfFrames.
+//
+//	stacktraceEncoder:
+//	  maxDepth: 20
+//	  skipRuntime: true
+//
+// If value is string, it uses UnmarshalText.
+//
+//	stacktraceEncoder: frames
+func (e *StacktraceEncoder) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var cfg StacktraceFramesConfig
+	if err := unmarshal(&cfg); err == nil {
+		*e = StacktraceEncoderOfFrames(cfg)
+		return nil
+	}
+
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return e.UnmarshalText([]byte(s))
+}
+
+// UnmarshalJSON unmarshals JSON to a StacktraceEncoder as same way
+// UnmarshalYAML does.
+func (e *StacktraceEncoder) UnmarshalJSON(data []byte) error {
+	return e.UnmarshalYAML(func(v interface{}) error {
+		return json.Unmarshal(data, v)
+	})
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	. "go.uber.org/zap/zapcore"
+)
+
+const _testStack = "go.uber.org/zap.(*Logger).Info\n\t/src/zap/logger.go:10\n" +
+	"main.run\n\t/src/app/main.go:20\n" +
+	"main.main\n\t/src/app/main.go:5\n" +
+	"runtime.main\n\t/go/src/runtime/proc.go:250"
+
+func TestParseStacktrace(t *testing.T) {
+	frames, ok := ParseStacktrace(_testStack)
+	require.True(t, ok, "Expected stacktrace to parse.")
+	assert.Equal(t, []StackFrame{
+		{Function: "go.uber.org/zap.(*Logger).Info", File: "/src/zap/logger.go", Line: 10},
+		{Function: "main.run", File: "/src/app/main.go", Line: 20},
+		{Function: "main.main", File: "/src/app/main.go", Line: 5},
+		{Function: "runtime.main", File: "/go/src/runtime/proc.go", Line: 250},
+	}, frames, "Unexpected frames.")
+
+	for _, bad := range []string{
+		"main.main",
+		"main.main\n/src/app/main.go:5",
+		"main.main\n\t/src/app/main.go",
+		"main.main\n\t/src/app/main.go:five",
+	} {
+		_, ok := ParseStacktrace(bad)
+		assert.False(t, ok, "Expected %q not to parse.", bad)
+	}
+}
+
+func TestStacktraceFramesConfigSkips(t *testing.T) {
+	assert.False(t, StacktraceFramesConfig{MaxDepth: 1}.Filters(), "Expected a depth limit alone not to filter.")
+
+	cfg := StacktraceFramesConfig{
+		SkipRuntime:  true,
+		SkipZap:      true,
+		SkipPrefixes: []string{"net/http."},
+	}
+	assert.True(t, cfg.Filters(), "Expected config to filter.")
+	for _, fn := range []string{
+		"runtime.goexit",
+		"go.uber.org/zap.(*Logger).Info",
+		"go.uber.org/zap/zapcore.(*CheckedEntry).Write",
+		"net/http.HandlerFunc.ServeHTTP",
+	} {
+		assert.True(t, cfg.Skips(fn), "Expected %q to be skipped.", fn)
+	}
+	for _, fn := range []string{"main.main", "go.uber.org/zapper.Run", "net/http/httptest.NewServer"} {
+		assert.False(t, cfg.Skips(fn), "Expected %q to be kept.", fn)
+	}
+}
+
+func TestStacktraceEncoders(t *testing.T) {
+	filtered := StacktraceEncoderOfFrames(StacktraceFramesConfig{
+		MaxDepth:    1,
+		SkipRuntime: true,
+		SkipZap:     true,
+	})
+
+	tests := []struct {
+		desc            string
+		encoder         StacktraceEncoder
+		stack           string
+		expectedJSON    string
+		expectedConsole string
+	}{
+		{
+			desc:            "default",
+			stack:           "main.main\n\t/src/app/main.go:5",
+			expectedJSON:    `{"M":"hello","S":"main.main\n\t/src/app/main.go:5"}`,
+			expectedConsole: "hello\nmain.main\n\t/src/app/main.go:5",
+		},
+		{
+			desc:            "frames",
+			encoder:         FramesStacktraceEncoder,
+			stack:           "main.main\n\t/src/app/main.go:5",
+			expectedJSON:    `{"M":"hello","S":[{"function":"main.main","file":"/src/app/main.go","line":5}]}`,
+			expectedConsole: "hello\nmain.main\n\t/src/app/main.go:5",
+		},
+		{
+			desc:            "filtered frames",
+			encoder:         filtered,
+			stack:           _testStack,
+			expectedJSON:    `{"M":"hello","S":[{"function":"main.run","file":"/src/app/main.go","line":20}]}`,
+			expectedConsole: "hello\nmain.run\n\t/src/app/main.go:20",
+		},
+		{
+			desc:            "unparseable",
+			encoder:         FramesStacktraceEncoder,
+			stack:           "not a stacktrace",
+			expectedJSON:    `{"M":"hello","S":"not a stacktrace"}`,
+			expectedConsole: "hello\nnot a stacktrace",
+		},
+		{
+			desc:            "no-op",
+			encoder:         func(string, PrimitiveArrayEncoder) {},
+			stack:           "main.main\n\t/src/app/main.go:5",
+			expectedJSON:    `{"M":"hello","S":"main.main\n\t/src/app/main.go:5"}`,
+			expectedConsole: "hello\nmain.main\n\t/src/app/main.go:5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			cfg := EncoderConfig{
+				MessageKey:       "M",
+				StacktraceKey:    "S",
+				EncodeStacktrace: tt.encoder,
+			}
+			ent := Entry{Message: "hello", Stack: tt.stack}
+
+			buf, err := NewJSONEncoder(cfg).EncodeEntry(ent, nil)
+			require.NoError(t, err, "Unexpected error encoding entry.")
+			var decoded interface{}
+			assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded), "Expected valid JSON.")
+			assert.JSONEq(t, tt.expectedJSON, buf.String(), "Unexpected JSON output.")
+			buf.Free()
+
+			buf, err = NewConsoleEncoder(cfg).EncodeEntry(ent, nil)
+			require.NoError(t, err, "Unexpected error encoding entry.")
+			assert.Equal(t, tt.expectedConsole+"\n", buf.String(), "Unexpected console output.")
+			buf.Free()
+		})
+	}
+}
+
+func TestStacktraceEncoderParse(t *testing.T) {
+	const stack = "main.main\n\t/src/app/main.go:5\nmain.run\n\t/src/app/main.go:20"
+	tests := []struct {
+		yamlDoc  string
+		expected interface{}
+	}{
+		{"stacktraceEncoder: string", stack},
+		{"stacktraceEncoder: frames", []interface{}{
+			map[string]interface{}{"function": "main.main", "file": "/src/app/main.go", "line": 5},
+			map[string]interface{}{"function": "main.run", "file": "/src/app/main.go", "line": 20},
+		}},
+		{"stacktraceEncoder: {maxDepth: 1, skipPrefixes: [main.main]}", []interface{}{
+			map[string]interface{}{"function": "main.run", "file": "/src/app/main.go", "line": 20},
+		}},
+	}
+
+	for _, tt := range tests {
+		var cfg EncoderConfig
+		require.NoError(t, yaml.Unmarshal([]byte(tt.yamlDoc), &cfg), "Unexpected error unmarshaling %q.", tt.yamlDoc)
+		assertAppended(t, tt.expected, func(arr ArrayEncoder) { cfg.EncodeStacktrace(stack, arr) }, "Unexpected output for %q.", tt.yamlDoc)
+	}
+
+	var cfg EncoderConfig
+	require.NoError(t, json.Unmarshal([]byte(`{"stacktraceEncoder": {"skipRuntime": true}}`), &cfg), "Unexpected error unmarshaling JSON.")
+	assert.NotNil(t, cfg.EncodeStacktrace, "Expected stacktrace encoder from JSON.")
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeZone is the location that entry timestamps are converted to before
+// they're encoded. In text, it's "UTC", "Local", or an IANA time zone name like
+// "America/New_York". The zero value leaves timestamps in whatever location
+// they carry, which is Local for timestamps taken by the Logger.
+type TimeZone struct {
+	loc *time.Location
+}
+
+// NewTimeZone builds a TimeZone for the given location. A nil location
+// produces the zero TimeZone.
+func NewTimeZone(loc *time.Location) TimeZone {
+	return TimeZone{loc: loc}
+}
+
+// Location returns the location of the time zone, or nil for the zero value.
+func (z TimeZone) Location() *time.Location {
+	return z.loc
+}
+
+// String returns the name of the time zone, or an empty string for the zero
+// value.
+func (z TimeZone) String() string {
+	if z.loc == nil {
+		return ""
+	}
+	return z.loc.String()
+}
+
+// MarshalText marshals the TimeZone to its name.
+func (z TimeZone) MarshalText() ([]byte, error) {
+	return []byte(z.String()), nil
+}
+
+// UnmarshalText unmarshals text to a TimeZone. Names are looked up with
+// time.LoadLocation, so IANA names need a time zone database on the system
+// or time/tzdata in the binary. Empty text unmarshals to the zero value.
+func (z *TimeZone) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*z = TimeZone{}
+		return nil
+	}
+	loc, err := time.LoadLocation(string(text))
+	if err != nil {
+		return fmt.Errorf("unrecognized time zone: %q", text)
+	}
+	*z = TimeZone{loc: loc}
+	return nil
+}
+
+// In converts t to the time zone, leaving it unchanged for the zero value.
+func (z TimeZone) In(t time.Time) time.Time {
+	if z.loc == nil {
+		return t
+	}
+	return t.In(z.loc)
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"go.uber.org/zap"
+	. "go.uber.org/zap/zapcore"
+)
+
+func TestTimeZoneText(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected *time.Location
+	}{
+		{"", nil},
+		{"UTC", time.UTC},
+		{"Local", time.Local},
+	}
+
+	for _, tt := range tests {
+		var tz TimeZone
+		require.NoError(t, tz.UnmarshalText([]byte(tt.text)), "Unexpected error unmarshaling %q.", tt.text)
+		assert.Equal(t, tt.expected, tz.Location(), "Unexpected location for %q.", tt.text)
+
+		text, err := tz.MarshalText()
+		require.NoError(t, err, "Unexpected error marshaling %v.", tz)
+		assert.Equal(t, tt.text, string(text), "Unexpected round-trip for %q.", tt.text)
+	}
+
+	var tz TimeZone
+	require.NoError(t, tz.UnmarshalText([]byte("Asia/Tokyo")), "Unexpected error unmarshaling IANA name.")
+	assert.Equal(t, "Asia/Tokyo", tz.String(), "Unexpected time zone name.")
+	assert.Error(t, tz.UnmarshalText([]byte("Nowhere/Special")), "Expected error unmarshaling unknown time zone.")
+
+	moment := time.Unix(0, 0)
+	assert.Equal(t, moment, TimeZone{}.In(moment), "Expected zero TimeZone to leave times unchanged.")
+	assert.Equal(t, time.UTC, NewTimeZone(time.UTC).In(moment).Location(), "Expected time to be converted.")
+}
+
+func TestEncoderTimeZoneAndFieldTime(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err, "Failed to load time zone.")
+
+	cfg := EncoderConfig{
+		TimeKey:         "T",
+		MessageKey:      "M",
+		EncodeTime:      RFC3339TimeEncoder,
+		EncodeFieldTime: EpochTimeEncoder,
+		TimeZone:        NewTimeZone(tokyo),
+	}
+	ent := Entry{Time: time.Unix(100, 0).UTC(), Message: "hello"}
+	fields := []Field{zap.Time("t", time.Unix(200, 0))}
+
+	tests := []struct {
+		enc      Encoder
+		expected string
+	}{
+		{NewJSONEncoder(cfg), `{"T":"1970-01-01T09:01:40+09:00","M":"hello","t":200}`},
+		{NewConsoleEncoder(cfg), `1970-01-01T09:01:40+09:00	hello	{"t": 200}`},
+	}
+	for _, tt := range tests {
+		buf, err := tt.enc.EncodeEntry(ent, fields)
+		require.NoError(t, err, "Unexpected error encoding entry.")
+		assert.Equal(t, tt.expected+"\n", buf.String(), "Unexpected output.")
+		buf.Free()
+	}
+}
+
+func TestEncoderConfigTimeZoneParse(t *testing.T) {
+	var fromYAML EncoderConfig
+	require.NoError(t, yaml.Unmarshal([]byte("timeZone: Asia/Tokyo\nfieldTimeEncoder: iso8601"), &fromYAML), "Unexpected error unmarshaling YAML.")
+	assert.Equal(t, "Asia/Tokyo", fromYAML.TimeZone.String(), "Unexpected time zone from YAML.")
+	assert.NotNil(t, fromYAML.EncodeFieldTime, "Expected field time encoder from YAML.")
+
+	var fromJSON EncoderConfig
+	require.NoError(t, json.Unmarshal([]byte(`{"timeZone": "UTC"}`), &fromJSON), "Unexpected error unmarshaling JSON.")
+	assert.Equal(t, time.UTC, fromJSON.TimeZone.Location(), "Unexpected time zone from JSON.")
+
+	assert.Error(t, yaml.Unmarshal([]byte("timeZone: Nowhere/Special"), &EncoderConfig{}), "Expected error for unknown time zone.")
+}
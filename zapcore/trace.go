@@ -0,0 +1,105 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TraceContext identifies the span of a distributed trace that an entry
+// belongs to, like the trace-id, parent-id and trace-flags parts of a W3C
+// traceparent header. Tracing libraries' span contexts are easily adapted to
+// it, so that zap doesn't depend on any particular tracing library.
+//
+// Samplers built with the KeepSampledTraces option always keep entries whose
+// Trace is sampled, or whose context includes a field carrying a sampled
+// TraceContext.
+type TraceContext interface {
+	// TraceID returns the trace ID as 32 lowercase hex digits.
+	TraceID() string
+	// SpanID returns the span ID as 16 lowercase hex digits.
+	SpanID() string
+	// IsSampled reports whether the trace is being recorded.
+	IsSampled() bool
+}
+
+// traceparent is a TraceContext parsed from a W3C traceparent header.
+type traceparent struct {
+	traceID, spanID string
+	sampled         bool
+}
+
+func (tp traceparent) TraceID() string { return tp.traceID }
+func (tp traceparent) SpanID() string  { return tp.spanID }
+func (tp traceparent) IsSampled() bool { return tp.sampled }
+
+// ParseTraceparent parses the value of a W3C traceparent header, such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func ParseTraceparent(header string) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 {
+		return nil, fmt.Errorf("invalid traceparent %q: expected four parts", header)
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	switch {
+	case len(version) != 2 || !isLowerHex(version) || version == "ff":
+		return nil, fmt.Errorf("invalid traceparent %q: bad version", header)
+	case version == "00" && len(parts) != 4:
+		return nil, fmt.Errorf("invalid traceparent %q: expected four parts", header)
+	case len(traceID) != 32 || !isLowerHex(traceID) || isZeroHex(traceID):
+		return nil, fmt.Errorf("invalid traceparent %q: bad trace ID", header)
+	case len(spanID) != 16 || !isLowerHex(spanID) || isZeroHex(spanID):
+		return nil, fmt.Errorf("invalid traceparent %q: bad span ID", header)
+	case len(flags) != 2 || !isLowerHex(flags):
+		return nil, fmt.Errorf("invalid traceparent %q: bad trace flags", header)
+	}
+	return traceparent{
+		traceID: traceID,
+		spanID:  spanID,
+		// The sampled flag is the lowest bit of the trace flags.
+		sampled: strings.IndexByte("13579bdf", flags[1]) >= 0,
+	}, nil
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZeroHex(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+// sampledTrace reports whether the last field carrying a TraceContext, if
+// any, belongs to a sampled trace.
+func sampledTrace(fields []Field, sampled bool) bool {
+	for _, f := range fields {
+		if tc, ok := f.Interface.(TraceContext); ok {
+			sampled = tc.IsSampled()
+		}
+	}
+	return sampled
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore_test
+
+import (
+	"testing"
+
+	. "go.uber.org/zap/zapcore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		header  string
+		sampled bool
+		wantErr string
+	}{
+		{header: "00-" + traceID + "-" + spanID + "-01", sampled: true},
+		{header: "00-" + traceID + "-" + spanID + "-00", sampled: false},
+		{header: " 00-" + traceID + "-" + spanID + "-03 ", sampled: true},
+		{header: "01-" + traceID + "-" + spanID + "-02-future", sampled: false},
+		{header: "", wantErr: "expected four parts"},
+		{header: "00-" + traceID + "-" + spanID + "-01-extra", wantErr: "expected four parts"},
+		{header: "ff-" + traceID + "-" + spanID + "-01", wantErr: "bad version"},
+		{header: "00-" + "4BF92F3577B34DA6A3CE929D0E0E4736" + "-" + spanID + "-01", wantErr: "bad trace ID"},
+		{header: "00-00000000000000000000000000000000-" + spanID + "-01", wantErr: "bad trace ID"},
+		{header: "00-" + traceID + "-0000000000000000-01", wantErr: "bad span ID"},
+		{header: "00-" + traceID + "-" + spanID + "-1", wantErr: "bad trace flags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			tc, err := ParseTraceparent(tt.header)
+			if tt.wantErr != "" {
+				require.Error(t, err, "Expected an error.")
+				assert.Contains(t, err.Error(), tt.wantErr, "Unexpected error.")
+				return
+			}
+			require.NoError(t, err, "Unexpected error.")
+			assert.Equal(t, traceID, tc.TraceID(), "Unexpected trace ID.")
+			assert.Equal(t, spanID, tc.SpanID(), "Unexpected span ID.")
+			assert.Equal(t, tt.sampled, tc.IsSampled(), "Unexpected sampled flag.")
+		})
+	}
+}
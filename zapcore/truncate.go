@@ -0,0 +1,228 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"strconv"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+)
+
+// _truncatedKey is the key the JSON encoder uses to mark entries that were
+// cut short to respect EncoderConfig.MaxEntrySize.
+const _truncatedKey = "truncated"
+
+// _maxTruncationMarkerLen is an upper bound on the length of the markers
+// written by appendTruncationMarker, for reserving space before truncating.
+const _maxTruncationMarkerLen = len("…(truncated 1023.9GB)")
+
+// arrayLimit tracks the elements of the array being appended, for enforcing
+// EncoderConfig.MaxArrayLength.
+type arrayLimit struct {
+	depth int // nesting depth of the array's elements; zero if not tracking
+	n     int // number of elements started so far
+	cut   int // buffer offset just past the last element to keep
+}
+
+// truncateString cuts s to at most max bytes, backing off to the nearest rune
+// boundary. It returns the kept prefix and the number of bytes removed. A
+// non-positive max disables truncation.
+func truncateString(s string, max int) (string, int) {
+	if max <= 0 || len(s) <= max {
+		return s, 0
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut], len(s) - cut
+}
+
+// truncateByteString is truncateString for []byte.
+func truncateByteString(s []byte, max int) ([]byte, int) {
+	if max <= 0 || len(s) <= max {
+		return s, 0
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut], len(s) - cut
+}
+
+// appendTruncationMarker appends a human-readable note that n bytes were
+// removed, like "…(truncated 1.2MB)".
+func appendTruncationMarker(buf *buffer.Buffer, n int) {
+	buf.AppendString("…(truncated ")
+	appendSize(buf, n)
+	buf.AppendByte(')')
+}
+
+// appendSize appends a byte count using binary units.
+func appendSize(buf *buffer.Buffer, n int) {
+	const unit = 1024
+	if n < unit {
+		buf.AppendInt(int64(n))
+		buf.AppendByte('B')
+		return
+	}
+	size, suffix := float64(n)/unit, "KB"
+	for _, s := range []string{"MB", "GB"} {
+		if size < unit {
+			break
+		}
+		size, suffix = size/unit, s
+	}
+	buf.AppendString(strconv.FormatFloat(size, 'f', 1, 64))
+	buf.AppendString(suffix)
+}
+
+func (enc *jsonEncoder) maxStringLength() int {
+	if enc.EncoderConfig == nil {
+		return 0
+	}
+	return enc.MaxStringLength
+}
+
+// exceedsMaxDepth reports whether appending another array or object would
+// nest values more deeply than EncoderConfig.MaxDepth allows.
+func (enc *jsonEncoder) exceedsMaxDepth() bool {
+	return enc.EncoderConfig != nil && enc.MaxDepth > 0 && enc.nesting >= enc.MaxDepth
+}
+
+// appendDepthMarker appends a note in place of an array or object that would
+// nest too deeply. Like the other markers, it's written straight to the
+// buffer so that MaxStringLength doesn't cut it short.
+func (enc *jsonEncoder) appendDepthMarker(kind string) {
+	enc.addElementSeparator()
+	enc.buf.AppendString(`"…(truncated nested `)
+	enc.buf.AppendString(kind)
+	enc.buf.AppendString(`)"`)
+}
+
+// openArray starts tracking the elements of an array, returning the state of
+// the enclosing array so that closeArray can restore it.
+func (enc *jsonEncoder) openArray() arrayLimit {
+	outer := enc.array
+	if enc.EncoderConfig != nil && enc.MaxArrayLength > 0 {
+		enc.array = arrayLimit{depth: enc.nesting}
+	} else {
+		enc.array = arrayLimit{}
+	}
+	return outer
+}
+
+// countElement notes the start of an array element. Once the array has more
+// than MaxArrayLength elements, each new element overwrites the last so that
+// the buffer doesn't grow.
+func (enc *jsonEncoder) countElement() {
+	enc.array.n++
+	switch max := enc.MaxArrayLength; {
+	case enc.array.n == max+1:
+		enc.array.cut = enc.buf.Len()
+	case enc.array.n > max+1:
+		enc.buf.Truncate(enc.array.cut)
+	}
+}
+
+// closeArray drops any elements past MaxArrayLength, replacing them with a
+// marker, and restores the state of the enclosing array.
+func (enc *jsonEncoder) closeArray(outer arrayLimit) {
+	if enc.array.depth != 0 && enc.array.n > enc.MaxArrayLength {
+		dropped := enc.array.n - enc.MaxArrayLength
+		enc.buf.Truncate(enc.array.cut)
+		enc.array = arrayLimit{}
+		enc.addElementSeparator()
+		enc.buf.AppendString(`"…(truncated `)
+		enc.buf.AppendInt(int64(dropped))
+		enc.buf.AppendString(` elements)"`)
+	}
+	enc.array = outer
+}
+
+// truncateEntry drops trailing fields so that the entry, once closed, fits in
+// EncoderConfig.MaxEntrySize. If not even the marker fits, the entry is left
+// empty. All namespaces must already be closed.
+func (enc *jsonEncoder) truncateEntry() {
+	lineEnding := enc.LineEnding
+	if lineEnding == "" {
+		lineEnding = DefaultLineEnding
+	}
+	budget := enc.MaxEntrySize - len(lineEnding) - 1 // for the closing brace
+	if enc.buf.Len() <= budget {
+		return
+	}
+
+	// Keep as many complete fields as fit alongside the marker. Fields
+	// inside namespaces are eligible too, as long as there's room to close
+	// the namespaces.
+	enc.closeKeys(0)
+	reserve := len(`,"":""`) + len(_truncatedKey) + _maxTruncationMarkerLen
+	cut, scope := 1, 0 // just past the opening brace
+	for _, k := range enc.keys {
+		if k.end > cut && k.end+k.scope+reserve <= budget {
+			cut, scope = k.end, k.scope
+		}
+	}
+
+	removed := enc.buf.Len() - cut
+	enc.buf.Truncate(cut)
+	enc.keys = enc.keys[:0]
+	for i := 0; i < scope; i++ {
+		enc.buf.AppendByte('}')
+	}
+	marked := enc.buf.Len()
+	enc.addKey(_truncatedKey)
+	enc.buf.AppendByte('"')
+	appendTruncationMarker(enc.buf, removed)
+	enc.buf.AppendByte('"')
+	if enc.buf.Len() > budget {
+		enc.buf.Truncate(marked)
+	}
+}
+
+// truncateLine cuts a line of console output so that, with its line ending,
+// it fits in EncoderConfig.MaxEntrySize. If there's no room for the marker,
+// the line is cut short without one.
+func (c consoleEncoder) truncateLine(line *buffer.Buffer, lineEnding string) {
+	budget := c.MaxEntrySize - len(lineEnding)
+	if c.MaxEntrySize <= 0 || line.Len() <= budget {
+		return
+	}
+	marked := budget >= _maxTruncationMarkerLen
+	cut := budget
+	if marked {
+		cut -= _maxTruncationMarkerLen
+	}
+	if cut < 0 {
+		cut = 0
+	}
+	bs := line.Bytes()
+	for cut > 0 && !utf8.RuneStart(bs[cut]) {
+		cut--
+	}
+	removed := line.Len() - cut
+	line.Truncate(cut)
+	if marked {
+		appendTruncationMarker(line, removed)
+	}
+}
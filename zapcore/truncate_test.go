@@ -0,0 +1,189 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.uber.org/zap"
+	. "go.uber.org/zap/zapcore"
+)
+
+func TestEncoderLimits(t *testing.T) {
+	ints := zap.Ints("ints", []int{1, 2, 3, 4, 5})
+	nested := zap.Object("a", ObjectMarshalerFunc(func(enc ObjectEncoder) error {
+		return enc.AddObject("b", ObjectMarshalerFunc(func(enc ObjectEncoder) error {
+			enc.AddInt("c", 1)
+			return enc.AddArray("d", ArrayMarshalerFunc(func(enc ArrayEncoder) error {
+				enc.AppendInt(2)
+				return nil
+			}))
+		}))
+	}))
+
+	tests := []struct {
+		desc            string
+		configure       func(*EncoderConfig)
+		message         string
+		context         []Field
+		fields          []Field
+		expectedJSON    string
+		expectedConsole string
+	}{
+		{
+			desc:            "no limits",
+			configure:       func(*EncoderConfig) {},
+			fields:          []Field{ints, nested},
+			expectedJSON:    `{"L":"info","M":"hello","ints":[1,2,3,4,5],"a":{"b":{"c":1,"d":[2]}}}`,
+			expectedConsole: `info	hello	{"ints": [1, 2, 3, 4, 5], "a": {"b": {"c": 1, "d": [2]}}}`,
+		},
+		{
+			desc:            "string length",
+			configure:       func(c *EncoderConfig) { c.MaxStringLength = 5 },
+			message:         "hello world",
+			fields:          []Field{zap.String("s", "abcdefgh"), zap.String("short", "abcde"), zap.ByteString("bs", []byte("hé€"))},
+			expectedJSON:    `{"L":"info","M":"hello…(truncated 6B)","s":"abcde…(truncated 3B)","short":"abcde","bs":"hé…(truncated 3B)"}`,
+			expectedConsole: `info	hello…(truncated 6B)	{"s": "abcde…(truncated 3B)", "short": "abcde", "bs": "hé…(truncated 3B)"}`,
+		},
+		{
+			desc:            "array length",
+			configure:       func(c *EncoderConfig) { c.MaxArrayLength = 2 },
+			context:         []Field{ints},
+			fields:          []Field{zap.Ints("short", []int{1, 2}), nested},
+			expectedJSON:    `{"L":"info","M":"hello","ints":[1,2,"…(truncated 3 elements)"],"short":[1,2],"a":{"b":{"c":1,"d":[2]}}}`,
+			expectedConsole: `info	hello	{"ints": [1, 2, "…(truncated 3 elements)"], "short": [1, 2], "a": {"b": {"c": 1, "d": [2]}}}`,
+		},
+		{
+			desc:      "nested array length",
+			configure: func(c *EncoderConfig) { c.MaxArrayLength = 1 },
+			fields: []Field{zap.Array("outer", ArrayMarshalerFunc(func(enc ArrayEncoder) error {
+				for i := 0; i < 3; i++ {
+					if err := enc.AppendArray(ArrayMarshalerFunc(func(enc ArrayEncoder) error {
+						enc.AppendInt(1)
+						enc.AppendInt(2)
+						return nil
+					})); err != nil {
+						return err
+					}
+				}
+				return nil
+			}))},
+			expectedJSON:    `{"L":"info","M":"hello","outer":[[1,"…(truncated 1 elements)"],"…(truncated 2 elements)"]}`,
+			expectedConsole: `info	hello	{"outer": [[1, "…(truncated 1 elements)"], "…(truncated 2 elements)"]}`,
+		},
+		{
+			desc:            "depth",
+			configure:       func(c *EncoderConfig) { c.MaxDepth = 2 },
+			fields:          []Field{nested, ints},
+			expectedJSON:    `{"L":"info","M":"hello","a":{"b":{"c":1,"d":"…(truncated nested array)"}},"ints":[1,2,3,4,5]}`,
+			expectedConsole: `info	hello	{"a": {"b": {"c": 1, "d": "…(truncated nested array)"}}, "ints": [1, 2, 3, 4, 5]}`,
+		},
+		{
+			desc:            "entry size",
+			configure:       func(c *EncoderConfig) { c.MaxEntrySize = 100 },
+			context:         []Field{zap.String("ctx", "value")},
+			fields:          []Field{zap.Namespace("ns"), zap.Int("n", 1), zap.String("long", strings.Repeat("x", 100))},
+			expectedJSON:    `{"L":"info","M":"hello","ctx":"value","ns":{"n":1},"truncated":"…(truncated 111B)"}`,
+			expectedConsole: `info	hello	{"ctx": "value", "ns": {"n": 1, "long": "xxxxxxxxxxxxxxxxxxxxxxxx…(truncated 79B)`,
+		},
+		{
+			desc:            "depth with string length",
+			configure:       func(c *EncoderConfig) { c.MaxDepth = 1; c.MaxStringLength = 3 },
+			fields:          []Field{nested},
+			expectedJSON:    `{"L":"inf…(truncated 1B)","M":"hel…(truncated 2B)","a":{"b":"…(truncated nested object)"}}`,
+			expectedConsole: `info	hel…(truncated 2B)	{"a": {"b": "…(truncated nested object)"}}`,
+		},
+		{
+			desc:            "entry size too small for any field",
+			configure:       func(c *EncoderConfig) { c.MaxEntrySize = 40 },
+			fields:          []Field{zap.String("long", strings.Repeat("x", 40))},
+			expectedJSON:    `{"truncated":"…(truncated 72B)"}`,
+			expectedConsole: `info	hello	{"lon…(truncated 47B)`,
+		},
+		{
+			desc:            "entry size too small for the marker",
+			configure:       func(c *EncoderConfig) { c.MaxEntrySize = 10 },
+			fields:          []Field{zap.Int("n", 1)},
+			expectedJSON:    `{}`,
+			expectedConsole: `info	hell`,
+		},
+		{
+			desc:            "entry size below the minimum",
+			configure:       func(c *EncoderConfig) { c.MaxEntrySize = 1 },
+			fields:          []Field{zap.Int("n", 1)},
+			expectedJSON:    `{}`,
+			expectedConsole: ``,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			cfg := EncoderConfig{
+				LevelKey:    "L",
+				MessageKey:  "M",
+				EncodeLevel: LowercaseLevelEncoder,
+			}
+			tt.configure(&cfg)
+			ent := Entry{Level: InfoLevel, Message: "hello"}
+			if tt.message != "" {
+				ent.Message = tt.message
+			}
+
+			encoders := []struct {
+				enc      Encoder
+				expected string
+			}{
+				{NewJSONEncoder(cfg), tt.expectedJSON},
+				{NewConsoleEncoder(cfg), tt.expectedConsole},
+			}
+			for _, e := range encoders {
+				for _, f := range tt.context {
+					f.AddTo(e.enc)
+				}
+				buf, err := e.enc.EncodeEntry(ent, tt.fields)
+				require.NoError(t, err, "Unexpected error encoding entry.")
+				assert.Equal(t, e.expected+"\n", buf.String(), "Unexpected output.")
+				if limit := cfg.MaxEntrySize; limit > 0 {
+					if min := len("{}\n"); limit < min {
+						limit = min
+					}
+					assert.True(t, buf.Len() <= limit, "Expected entry to fit in %d bytes, got %d.", limit, buf.Len())
+				}
+				buf.Free()
+			}
+		})
+	}
+}
+
+func TestTruncationMarkerSize(t *testing.T) {
+	// appendSize is tested directly in truncate_impl_test.go; this just
+	// checks that markers use it.
+	enc := NewJSONEncoder(EncoderConfig{MaxStringLength: 1})
+	enc.AddString("k", strings.Repeat("x", 1230))
+	buf, err := enc.EncodeEntry(Entry{}, nil)
+	require.NoError(t, err, "Unexpected error encoding entry.")
+	assert.Equal(t, `{"k":"x…(truncated 1.2KB)"}`+"\n", buf.String(), "Unexpected marker.")
+	buf.Free()
+}